@@ -0,0 +1,120 @@
+package pcan
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unsafe"
+)
+
+/* Detection of the baud rate used on a bus. */
+
+// Detects the baud rate of a bus by listening with every candidate baud rate
+// handle: The handle of a PCAN Channel which is not initialized yet
+// candidates: Baud rates to try in the given order
+// probe: Time to listen with a single candidate
+// Note: Listen-only mode is set before the channel is initialized, so it never disturbs the bus with error frames.
+// Fails if the hardware does not support listen-only mode. A candidate matches if a data
+// frame is received without any bus error. The channel is uninitialized after every probe.
+// Note: Fails with ErrAlreadyInitialized if the channel is initialized already, its bus is not touched.
+func DetectBaudrate(handle TPCANHandle, candidates []TPCANBaudrate, probe time.Duration) (TPCANBaudrate, error) {
+	for _, baudRate := range candidates {
+		match, err := probeBaudrate(handle, baudRate, probe)
+		if err != nil {
+			return 0, err
+		}
+		if match {
+			return baudRate, nil
+		}
+	}
+	return 0, errors.New("no candidate baud rate matches the bus traffic")
+}
+
+// Detects the baud rate of a bus and initializes the channel with it in normal (not listen-only) mode
+// handle: The handle of a PCAN Channel which is not initialized yet
+// candidates: Baud rates to try in the given order
+// probe: Time to listen with a single candidate
+// Note: Fails with ErrAlreadyInitialized if the channel is initialized already, the returned bus is never shared
+func OpenAutoBaud(handle TPCANHandle, candidates []TPCANBaudrate, probe time.Duration) (TPCANStatus, *TPCANBus, TPCANBaudrate, error) {
+	baudRate, err := DetectBaudrate(handle, candidates, probe)
+	if err != nil {
+		return PCAN_ERROR_UNKNOWN, nil, 0, err
+	}
+
+	status, bus, created, err := initializeBasic(handle, baudRate, nil)
+	if status != PCAN_ERROR_OK || err != nil {
+		return status, nil, baudRate, err
+	}
+	if !created {
+		return PCAN_ERROR_ILLOPERATION, nil, baudRate, fmt.Errorf("%w: handle 0x%X", ErrAlreadyInitialized, handle)
+	}
+	status, err = bus.SetListenOnly(false)
+	if status != PCAN_ERROR_OK || err != nil {
+		bus.Uninitialize()
+		return status, nil, baudRate, err
+	}
+	return status, bus, baudRate, nil
+}
+
+// listens with a single baud rate and checks if data frames are received without bus errors
+// Note: Listen-only mode is set on the uninitialized handle, so the controller never takes part in the bus
+// communication with a possibly wrong baud rate
+func probeBaudrate(handle TPCANHandle, baudRate TPCANBaudrate, probe time.Duration) (bool, error) {
+	preset := false
+	listenOnly := func() (TPCANStatus, error) {
+		status, err := setListenOnlyValue(handle, PCAN_PARAMETER_ON)
+		if status != PCAN_ERROR_OK || err != nil {
+			return status, errors.Join(errors.New("listen-only mode is required for baud rate detection"), err)
+		}
+		preset = true
+		return status, nil
+	}
+	status, bus, created, err := initializeBasic(handle, baudRate, listenOnly)
+	if preset {
+		// a later initialization of the handle must not be listen-only
+		defer setListenOnlyValue(handle, PCAN_PARAMETER_OFF)
+	}
+	if created {
+		defer bus.Uninitialize()
+	}
+	if err != nil {
+		return false, err
+	}
+	if status != PCAN_ERROR_OK {
+		return false, errors.New("initializing channel for baud rate probe failed")
+	}
+	if !created {
+		return false, fmt.Errorf("%w: handle 0x%X is in use, the baud rate probe needs an own channel", ErrAlreadyInitialized, handle)
+	}
+	if _, err = bus.Reset(); err != nil {
+		return false, err
+	}
+
+	received := false
+	endTime := time.Now().Add(probe)
+	for time.Now().Before(endTime) {
+		status, msg, _, err := bus.ReadWithTimeout(int(max(time.Until(endTime).Milliseconds(), 1)))
+		if status&PCAN_ERROR_ANYBUSERR != 0 {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if msg != nil {
+			switch ClassifyMsgType(msg.MsgType) {
+			case FrameKindError:
+				return false, nil
+			case FrameKindData, FrameKindRTR:
+				received = true
+			}
+		}
+	}
+
+	status, err = bus.GetStatus()
+	return received && err == nil && status&PCAN_ERROR_ANYBUSERR == 0, err
+}
+
+// configures listen-only mode of a handle which does not need to be initialized
+func setListenOnlyValue(handle TPCANHandle, val TPCANParameterValue) (TPCANStatus, error) {
+	return APISetValue(handle, PCAN_LISTEN_ONLY, unsafe.Pointer(&val), uint32(unsafe.Sizeof(val)))
+}
@@ -0,0 +1,83 @@
+package pcan
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+/* Tests of the baud rate detection. */
+
+func TestDetectBaudrateExistingBus(t *testing.T) {
+	calls := countCalls(t, map[string]**apiProc{
+		"CAN_Initialize":   &pHandleInitialize,
+		"CAN_Uninitialize": &pHandleUninitialize,
+		"CAN_Reset":        &pHandleReset,
+		"CAN_SetValue":     &pHandleSetValue,
+	})
+	registerBus(t, &TPCANBus{Handle: PCAN_USBBUS1, Baudrate: PCAN_BAUD_500K, HWType: PCAN_DEFAULT_HW_TYPE,
+		IOPort: PCAN_DEFAULT_IO_PORT, Interrupt: PCAN_DEFAULT_INTERRUPT})
+
+	_, err := DetectBaudrate(PCAN_USBBUS1, []TPCANBaudrate{PCAN_BAUD_500K}, time.Millisecond)
+	if !errors.Is(err, ErrAlreadyInitialized) {
+		t.Fatalf("DetectBaudrate() = %v, want ErrAlreadyInitialized", err)
+	}
+	if len(calls) != 0 {
+		t.Errorf("driver calls = %v, the existing bus must not be touched", calls)
+	}
+}
+
+func TestProbeBaudrateListenOnlyBeforeInitialize(t *testing.T) {
+	oldUse := UseReceiveEvents
+	UseReceiveEvents = false
+	t.Cleanup(func() { UseReceiveEvents = oldUse })
+	stubRead(t)
+
+	var calls []string
+	record := func(name string) func(a ...uintptr) (uintptr, uintptr, error) {
+		return func(a ...uintptr) (uintptr, uintptr, error) {
+			calls = append(calls, name)
+			return uintptr(PCAN_ERROR_OK), 0, nil
+		}
+	}
+	stubProc(t, &pHandleInitialize, record("CAN_Initialize"))
+	stubProc(t, &pHandleUninitialize, record("CAN_Uninitialize"))
+	stubProc(t, &pHandleReset, record("CAN_Reset"))
+	stubProc(t, &pHandleGetStatus, record("CAN_GetStatus"))
+	stubProc(t, &pHandleSetValue, func(a ...uintptr) (uintptr, uintptr, error) {
+		if TPCANParameter(a[1]) == PCAN_LISTEN_ONLY {
+			calls = append(calls, fmt.Sprintf("listen-only %v", *argPtr[TPCANParameterValue](a[2])))
+		}
+		return uintptr(PCAN_ERROR_OK), 0, nil
+	})
+
+	if _, err := probeBaudrate(PCAN_USBBUS1, PCAN_BAUD_500K, time.Millisecond); err != nil {
+		t.Fatalf("probeBaudrate() = %v", err)
+	}
+	want := []string{
+		fmt.Sprintf("listen-only %v", PCAN_PARAMETER_ON), "CAN_Initialize", "CAN_Reset", "CAN_GetStatus",
+		"CAN_Uninitialize", fmt.Sprintf("listen-only %v", PCAN_PARAMETER_OFF),
+	}
+	if !slices.Equal(calls, want) {
+		t.Errorf("driver calls = %v, want %v", calls, want)
+	}
+}
+
+func TestProbeBaudrateWithoutListenOnly(t *testing.T) {
+	calls := countCalls(t, map[string]**apiProc{
+		"CAN_Initialize":   &pHandleInitialize,
+		"CAN_Uninitialize": &pHandleUninitialize,
+	})
+	stubProc(t, &pHandleSetValue, func(a ...uintptr) (uintptr, uintptr, error) {
+		return uintptr(PCAN_ERROR_ILLPARAMTYPE), 0, nil
+	})
+
+	if _, err := probeBaudrate(PCAN_USBBUS1, PCAN_BAUD_500K, time.Millisecond); err == nil {
+		t.Fatal("probeBaudrate() succeeded without listen-only mode")
+	}
+	if len(calls) != 0 {
+		t.Errorf("driver calls = %v, the channel must not be initialized without listen-only mode", calls)
+	}
+}
@@ -0,0 +1,143 @@
+package pcan
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+/* Bridging of CAN messages over TCP connections. */
+
+// Forwards all messages received on the bus to the connection and writes all messages received from the connection on the bus
+// conn: Connection to the remote side, e.g. a BridgeFromTCP on another machine
+// Note: Runs until ctx is cancelled, the remote side closes the connection or one direction fails; only the failure is returned
+// Note: Messages are exchanged in the MarshalBinary layout prefixed by their length as little endian uint16
+// Note: To unblock pending socket operations on shutdown the deadline of conn is set, the connection is not closed
+func BridgeToTCP(ctx context.Context, p *TPCANBus, conn net.Conn) error {
+	return runBridge(ctx, conn,
+		func(ctx context.Context) error {
+			for ctx.Err() == nil {
+				_, msg, _, err := p.ReadWithTimeout(monitorReadTimeout)
+				if err != nil {
+					return err
+				}
+				if msg == nil {
+					continue
+				}
+				if err = writeBridgeFrame(conn, msg); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		func(ctx context.Context) error {
+			for {
+				msg, err := readBridgeFrame(conn)
+				if err != nil {
+					return err
+				}
+				status, err := p.WriteContext(ctx, &msg)
+				if err != nil {
+					return err
+				}
+				if status != PCAN_ERROR_OK {
+					return fmt.Errorf("writing bridged message 0x%X failed with status 0x%X", msg.ID, status)
+				}
+			}
+		})
+}
+
+// Receives messages from a bus bridged by BridgeToTCP and sends messages to be written on that bus
+// conn: Connection to the machine running BridgeToTCP
+// onFrame: Called for every message received on the remote bus
+// send: Messages written on the remote bus, may be nil if nothing is sent
+// Note: Runs until ctx is cancelled, the remote side closes the connection or one direction fails; only the failure is returned
+// Note: To unblock pending socket operations on shutdown the deadline of conn is set, the connection is not closed
+func BridgeFromTCP(ctx context.Context, conn net.Conn, onFrame func(*TPCANMsg), send <-chan TPCANMsg) error {
+	return runBridge(ctx, conn,
+		func(ctx context.Context) error {
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case msg := <-send:
+					if err := writeBridgeFrame(conn, &msg); err != nil {
+						return err
+					}
+				}
+			}
+		},
+		func(ctx context.Context) error {
+			for {
+				msg, err := readBridgeFrame(conn)
+				if err != nil {
+					return err
+				}
+				onFrame(&msg)
+			}
+		})
+}
+
+// runs both directions of a bridge until ctx is cancelled or one of them fails and waits for both to stop
+func runBridge(ctx context.Context, conn net.Conn, directions ...func(context.Context) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(directions))
+	for _, direction := range directions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer cancel()
+			errs <- direction(ctx)
+		}()
+	}
+
+	<-ctx.Done()
+	// a deadline in the past unblocks pending reads and writes of the connection
+	conn.SetDeadline(time.Unix(1, 0))
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, net.ErrClosed) && !errors.Is(err, io.EOF) {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// writes a message prefixed by its encoded length
+func writeBridgeFrame(w io.Writer, msg *TPCANMsg) error {
+	data, err := msg.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	frame := binary.LittleEndian.AppendUint16(make([]byte, 0, 2+len(data)), uint16(len(data)))
+	_, err = w.Write(append(frame, data...))
+	return err
+}
+
+// reads a message prefixed by its encoded length, partial reads are continued until the message is complete
+func readBridgeFrame(r io.Reader) (TPCANMsg, error) {
+	var msg TPCANMsg
+	var length [2]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return msg, err
+	}
+	data := make([]byte, binary.LittleEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return msg, err
+	}
+	return msg, msg.UnmarshalBinary(data)
+}
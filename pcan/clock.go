@@ -0,0 +1,33 @@
+package pcan
+
+import "time"
+
+/* Source of time for the polling timeout handling, replaceable to drive timeouts deterministically. */
+
+// Provides the current time and sleeps, used by ReadWithTimeout() when polling the receive queue
+type Clock interface {
+	Now() time.Time        // Returns the current time
+	Sleep(d time.Duration) // Pauses for at least the given duration
+}
+
+// Clock backed by the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time        { return time.Now() }
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// Returns the clock used for the polling timeout handling
+func (p *TPCANBus) Clock() Clock {
+	if p.clock == nil {
+		return realClock{}
+	}
+	return p.clock
+}
+
+// Replaces the clock used for the polling timeout handling
+// clock: Clock to use, nil restores the real clock
+// Note: Affects ReadWithTimeout() and ReadOrError(). With ReadStrategyPolling the clock sleeps between two reads,
+// with ReadStrategyEvent only the remaining time is taken from it as the wait for the event is done by the system.
+func (p *TPCANBus) SetClock(clock Clock) {
+	p.clock = clock
+}
@@ -0,0 +1,66 @@
+package pcan
+
+import (
+	"testing"
+	"time"
+)
+
+/* Tests of the timeout handling of ReadWithTimeout with a fake clock. */
+
+// replaces the read of the receive queue with an empty queue which returns a message on the given read, zero never
+func stubReadAfter(t *testing.T, messageRead int) *int {
+	t.Helper()
+	reads := new(int)
+	oldRead := apiRead
+	apiRead = func(handle TPCANHandle) (TPCANStatus, TPCANMsg, TPCANTimestamp, error) {
+		*reads++
+		if *reads == messageRead {
+			return PCAN_ERROR_OK, TPCANMsg{ID: 0x123, MsgType: PCAN_MESSAGE_STANDARD}, TPCANTimestamp{}, nil
+		}
+		return PCAN_ERROR_QRCVEMPTY, TPCANMsg{}, TPCANTimestamp{}, nil
+	}
+	t.Cleanup(func() { apiRead = oldRead })
+	return reads
+}
+
+func TestReadWithTimeoutDeadline(t *testing.T) {
+	tests := []struct {
+		name        string
+		timeout     int
+		interval    time.Duration
+		messageRead int
+		wantMsg     bool
+		wantSlept   time.Duration
+		wantReads   int
+	}{
+		{"no timeout", 0, time.Millisecond, 0, false, 0, 1},
+		{"deadline reached", 10, time.Millisecond, 0, false, 10 * time.Millisecond, 11},
+		{"deadline not a multiple of the interval", 5, 2 * time.Millisecond, 0, false, 5 * time.Millisecond, 4},
+		{"message before deadline", 10, time.Millisecond, 5, true, 4 * time.Millisecond, 5},
+		{"message at deadline", 10, time.Millisecond, 11, true, 10 * time.Millisecond, 11},
+		{"message after deadline", 10, time.Millisecond, 12, false, 10 * time.Millisecond, 11},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reads := stubReadAfter(t, test.messageRead)
+			clock := newFakeClock()
+			bus := &TPCANBus{Handle: PCAN_USBBUS1}
+			bus.SetClock(clock)
+			bus.SetPollInterval(test.interval)
+
+			status, msg, _, err := bus.ReadWithTimeout(test.timeout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (msg != nil) != test.wantMsg {
+				t.Errorf("ReadWithTimeout() = %v, %+v, want a message %v", status, msg, test.wantMsg)
+			}
+			if !test.wantMsg && status != PCAN_ERROR_QRCVEMPTY {
+				t.Errorf("status = %v, want PCAN_ERROR_QRCVEMPTY", status)
+			}
+			if clock.slept != test.wantSlept || *reads != test.wantReads {
+				t.Errorf("slept %v with %v reads, want %v with %v reads", clock.slept, *reads, test.wantSlept, test.wantReads)
+			}
+		})
+	}
+}
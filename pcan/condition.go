@@ -0,0 +1,91 @@
+package pcan
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unsafe"
+)
+
+/* Observation of the availability of a channel, e.g. to notice other applications opening it. */
+
+// Returns the name of the channel condition, e.g. "PCAN_CHANNEL_OCCUPIED"
+func (c TPCANCHannelCondition) String() string {
+	switch c {
+	case PCAN_CHANNEL_UNAVAILABLE:
+		return "PCAN_CHANNEL_UNAVAILABLE"
+	case PCAN_CHANNEL_AVAILABLE:
+		return "PCAN_CHANNEL_AVAILABLE"
+	case PCAN_CHANNEL_OCCUPIED:
+		return "PCAN_CHANNEL_OCCUPIED"
+	case PCAN_CHANNEL_PCANVIEW:
+		return "PCAN_CHANNEL_PCANVIEW"
+	}
+	return fmt.Sprintf("PCAN_CHANNEL_CONDITION(0x%X)", uint32(c))
+}
+
+// Returns the condition of a channel which does not need to be initialized by this application
+// handle: The handle of a PCAN Channel
+func GetHandleChannelCondition(handle TPCANHandle) (TPCANStatus, TPCANCHannelCondition, error) {
+	if err := loadAPIImplicitly(); err != nil {
+		return PCAN_ERROR_NODRIVER, PCAN_CHANNEL_UNAVAILABLE, err
+	}
+	var condition TPCANCHannelCondition
+	status, err := APIGetValue(handle, PCAN_CHANNEL_CONDITION, unsafe.Pointer(&condition), uint32(unsafe.Sizeof(condition)))
+	return status, condition, err
+}
+
+// Event emitted by ChannelConditionWatcher when the condition of a channel changed
+type ChannelConditionEvent struct {
+	Handle    TPCANHandle           // Observed channel
+	Previous  TPCANCHannelCondition // Condition before the change
+	Condition TPCANCHannelCondition // Condition after the change
+	Time      time.Time             // Time the change was detected
+}
+
+// Periodically reads the condition of a channel and emits an event whenever it changed, e.g. from
+// PCAN_CHANNEL_AVAILABLE to PCAN_CHANNEL_OCCUPIED when another application connects to it or to
+// PCAN_CHANNEL_PCANVIEW when PCAN-View uses it
+// handle: The handle of a PCAN Channel
+// interval: Time between two checks
+// Note: The condition read when the watcher starts is the reference and not emitted itself, use
+// GetHandleChannelCondition() to get it. Failed reads are skipped. The returned channel is closed after ctx is cancelled.
+func ChannelConditionWatcher(ctx context.Context, handle TPCANHandle, interval time.Duration) <-chan ChannelConditionEvent {
+	events := make(chan ChannelConditionEvent, 8)
+
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		status, previous, err := GetHandleChannelCondition(handle)
+		known := status == PCAN_ERROR_OK && err == nil
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			status, condition, err := GetHandleChannelCondition(handle)
+			if status != PCAN_ERROR_OK || err != nil {
+				continue
+			}
+			if !known || condition == previous {
+				previous, known = condition, true
+				continue
+			}
+
+			event := ChannelConditionEvent{Handle: handle, Previous: previous, Condition: condition, Time: time.Now()}
+			previous = condition
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events
+}
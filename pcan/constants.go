@@ -1,5 +1,9 @@
 package pcan
 
+import (
+	"time"
+)
+
 type (
 	TPCANLanguage         uint16              // Represents a language chosen for the error messages
 	TPCANHandle           uint16              // Represents a PCAN hardware channel handle
@@ -13,6 +17,7 @@ type (
 	TPCANTraceFileValue   TPCANParameterValue // Represents a PCAN trace file parameter value
 	TPCANFeatureValue     TPCANParameterValue // Represents a PCAN feature parameter value
 	TPCANStatusValue      TPCANParameterValue // Represents a PCAN status parameter value
+	TPCANLANDirection     TPCANParameterValue // Represents the communication direction of a PCAN-LAN channel
 	TPCANMessageType      uint8               // Represents the type of a PCAN message
 	TPCANMode             uint8               // Represents a PCAN filter mode
 	TPCANBaudrate         uint16              // Represents a PCAN Baud rate register value (BTR0/BTR1 register values for the CAN controller)
@@ -29,9 +34,15 @@ const (
 	LENGTH_DATA_CAN_MESSAGE      = 8                        // maximum amount of bytes in an PCAN CAN message
 	LENGTH_DATA_CANFD_MESSAGE    = 64                       // maximum amount of bytes in can CAN FD message
 	MAX_LENGTH_HARDWARE_NAME     = 33                       // Maximum length of the name of a device: 32 characters + terminator
-	MAX_LENGHT_STRING_BUFFER     = 256                      // Maximum length of any string buffer sent or received from pcan dll
+	MAX_LENGHT_STRING_BUFFER     = 256                      // Maximum length of any string buffer sent or received from pcan dll, also the minimum size the driver requires for error texts
 	MAX_LENGTH_VERSION_STRING    = MAX_LENGHT_STRING_BUFFER // Maximum length of a version string: 255 characters + terminator
 	MAX_TRACE_FILE_SIZE_ACCEPTED = 100                      // Maximum size of a trace file in MB
+	MAX_STANDARD_ID              = TPCANMsgID(0x7FF)        // Highest 11-bit message identifier
+	MAX_EXTENDED_ID              = TPCANMsgID(0x1FFFFFFF)   // Highest 29-bit message identifier
+
+	DEFAULT_POLL_INTERVAL   = 250 * time.Microsecond // Default sleep between two reads if the receive queue is polled
+	SHUTDOWN_DRAIN_DURATION = 100 * time.Millisecond // Maximum time Shutdown() drains the receive queue
+	SHUTDOWN_DRAIN_LIMIT    = 32768                  // Maximum amount of messages drained by Shutdown()
 
 	PCAN_DEFAULT_HW_TYPE   TPCANType = PCAN_TYPE_ISA // Default hardware type for a plug-n-play channel
 	PCAN_DEFAULT_IO_PORT   uint32    = 0x02A0        // Default IO port for a plug-n-play channel
@@ -212,6 +223,9 @@ const (
 	PCAN_ATTACHED_CHANNELS        = TPCANParameter(43) // Get information about PCAN channels attached to a system
 	PCAN_ALLOW_ECHO_FRAMES        = TPCANParameter(44) // Echo messages reception status within a PCAN-Channel
 	PCAN_DEVICE_PART_NUMBER       = TPCANParameter(45) // Get the part number associated to a device
+	PCAN_HARD_RESET_STATUS        = TPCANParameter(46) // Activation status of hard reset processing via CAN_Reset calls
+	PCAN_LAN_CHANNEL_DIRECTION    = TPCANParameter(47) // Communication direction of a PCAN-Channel representing a PCAN-LAN interface
+	PCAN_DEVICE_GUID              = TPCANParameter(48) // Get the globally unique device identifier (GUID) associated to a device
 )
 
 // PCAN parameter values
@@ -244,11 +258,12 @@ const (
 )
 
 const (
-	TRACE_FILE_SINGLE    = TPCANTraceFileValue(0x00) // A single file is written until it size reaches PAN_TRACE_SIZE
-	TRACE_FILE_SEGMENTED = TPCANTraceFileValue(0x01) // Traced data is distributed in several files with size PAN_TRACE_SIZE
-	TRACE_FILE_DATE      = TPCANTraceFileValue(0x02) // Includes the date into the name of the trace file
-	TRACE_FILE_TIME      = TPCANTraceFileValue(0x04) // Includes the start time into the name of the trace file
-	TRACE_FILE_OVERWRITE = TPCANTraceFileValue(0x80) // Causes the overwriting of available traces (same name)
+	TRACE_FILE_SINGLE      = TPCANTraceFileValue(0x00)  // A single file is written until it size reaches PAN_TRACE_SIZE
+	TRACE_FILE_SEGMENTED   = TPCANTraceFileValue(0x01)  // Traced data is distributed in several files with size PAN_TRACE_SIZE
+	TRACE_FILE_DATE        = TPCANTraceFileValue(0x02)  // Includes the date into the name of the trace file
+	TRACE_FILE_TIME        = TPCANTraceFileValue(0x04)  // Includes the start time into the name of the trace file
+	TRACE_FILE_OVERWRITE   = TPCANTraceFileValue(0x80)  // Causes the overwriting of available traces (same name)
+	TRACE_FILE_DATA_LENGTH = TPCANTraceFileValue(0x100) // Causes using the data length column ('l') instead of the DLC column ('L') in the trace file
 )
 
 const (
@@ -262,6 +277,12 @@ const (
 	SERVICE_STATUS_RUNNING = TPCANStatusValue(0x04) // The service is running
 )
 
+const (
+	LAN_DIRECTION_READ       = TPCANLANDirection(0x01)                  // The PCAN-Channel is limited to incoming communication only
+	LAN_DIRECTION_WRITE      = TPCANLANDirection(0x02)                  // The PCAN-Channel is limited to outgoing communication only
+	LAN_DIRECTION_READ_WRITE = LAN_DIRECTION_READ | LAN_DIRECTION_WRITE // The PCAN-Channel communication is bidirectional
+)
+
 // Represents the type of a PCAN message
 const (
 	PCAN_MESSAGE_STANDARD = TPCANMessageType(0x00) // The PCAN message is a CAN Standard Frame (11-bit identifier)
@@ -316,6 +337,7 @@ const (
 	LOOKUP_DEVICE_ID         = TPCANLookupParameter("deviceid")         // Lookup channel by device id
 	LOOKUP_CONTROLLER_NUMBER = TPCANLookupParameter("controllernumber") // Lookup channel by CAN controller 0-based index
 	LOOKUP_IP_ADDRESS        = TPCANLookupParameter("ipaddress")        // Lookup channel by IP address (LAN channels only)
+	LOOKUP_DEVICE_GUID       = TPCANLookupParameter("deviceguid")       // Lookup channel by device unique identifier (USB channels only)
 )
 
 // Represents the configuration for a CAN bit rate
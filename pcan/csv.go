@@ -0,0 +1,121 @@
+package pcan
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/* Export and import of captured messages as CSV. */
+
+// columns of the CSV format
+var csvHeader = []string{"timestamp_us", "id_hex", "extended", "rtr", "dlc", "data_hex"}
+
+// Writes messages with their timestamps as CSV including a header row
+// msgs: Messages to write
+// ts: Timestamps of the messages, must have the same length as msgs
+// Note: Only the valid data bytes (DLC) are written
+func WriteCSV(w io.Writer, msgs []TPCANMsg, ts []TPCANTimestamp) error {
+	if len(msgs) != len(ts) {
+		return fmt.Errorf("amount of messages (%v) and timestamps (%v) differ", len(msgs), len(ts))
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	for i := range msgs {
+		msg := &msgs[i]
+		rtr := msg.MsgType&PCAN_MESSAGE_RTR != 0
+		data := ""
+		if !rtr {
+			data = strings.ToUpper(hex.EncodeToString(msg.Data[:min(msg.DLC, LENGTH_DATA_CAN_MESSAGE)]))
+		}
+		record := []string{
+			strconv.FormatUint(ts[i].TotalMicros(), 10),
+			strconv.FormatUint(uint64(msg.ID), 16),
+			strconv.FormatBool(msg.MsgType&PCAN_MESSAGE_EXTENDED != 0),
+			strconv.FormatBool(rtr),
+			strconv.FormatUint(uint64(msg.DLC), 10),
+			data}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Reads messages with their timestamps from CSV written by WriteCSV
+func ReadCSV(r io.Reader) ([]TPCANMsg, []TPCANTimestamp, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(csvHeader)
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) == 0 || strings.Join(records[0], ",") != strings.Join(csvHeader, ",") {
+		return nil, nil, fmt.Errorf("missing CSV header %v", strings.Join(csvHeader, ","))
+	}
+
+	msgs := make([]TPCANMsg, 0, len(records)-1)
+	timestamps := make([]TPCANTimestamp, 0, len(records)-1)
+	for i, record := range records[1:] {
+		msg, timestamp, err := parseCSVRecord(record)
+		if err != nil {
+			return msgs, timestamps, fmt.Errorf("invalid CSV record in line %v: %v", i+2, err)
+		}
+		msgs = append(msgs, msg)
+		timestamps = append(timestamps, timestamp)
+	}
+	return msgs, timestamps, nil
+}
+
+// parses a single CSV record into a message and its timestamp
+func parseCSVRecord(record []string) (TPCANMsg, TPCANTimestamp, error) {
+	var msg TPCANMsg
+
+	micros, err := strconv.ParseUint(record[0], 10, 64)
+	if err != nil {
+		return msg, TPCANTimestamp{}, err
+	}
+	id, err := strconv.ParseUint(record[1], 16, 32)
+	if err != nil {
+		return msg, TPCANTimestamp{}, err
+	}
+	extended, err := strconv.ParseBool(record[2])
+	if err != nil {
+		return msg, TPCANTimestamp{}, err
+	}
+	rtr, err := strconv.ParseBool(record[3])
+	if err != nil {
+		return msg, TPCANTimestamp{}, err
+	}
+	dlc, err := strconv.ParseUint(record[4], 10, 8)
+	if err != nil {
+		return msg, TPCANTimestamp{}, err
+	}
+	data, err := hex.DecodeString(record[5])
+	if err != nil {
+		return msg, TPCANTimestamp{}, err
+	}
+	if dlc > LENGTH_DATA_CAN_MESSAGE || len(data) > LENGTH_DATA_CAN_MESSAGE {
+		return msg, TPCANTimestamp{}, fmt.Errorf("dlc of %v or data length of %v exceeds maximum of %v", dlc, len(data), LENGTH_DATA_CAN_MESSAGE)
+	}
+	if err := validateID(TPCANMsgID(id), extended); err != nil {
+		return msg, TPCANTimestamp{}, err
+	}
+
+	msg.ID = TPCANMsgID(id)
+	msg.MsgType = msgTypeFor(extended)
+	if rtr {
+		msg.MsgType |= PCAN_MESSAGE_RTR
+	}
+	msg.DLC = uint8(dlc)
+	copy(msg.Data[:], data)
+	return msg, TimestampFromMicros(micros), nil
+}
@@ -0,0 +1,408 @@
+package pcan
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/* Decoding of CAN messages into signals defined in a DBC database. */
+
+const dbcExtendedFlag = 0x80000000 // flag set on message identifiers of extended frames in a DBC file
+
+var (
+	dbcMessageRegex = regexp.MustCompile(`^BO_\s+(\d+)\s+(\w+)\s*:\s*(\d+)`)
+	dbcSignalRegex  = regexp.MustCompile(`^SG_\s+(\w+)\s*(M|m\d+)?\s*:\s*(\d+)\|(\d+)@([01])([+-])\s*\(([^,]+),([^)]+)\)\s*\[([^|]+)\|([^\]]+)\]\s*"([^"]*)"`)
+)
+
+// A database of message and signal definitions loaded from a DBC file
+type Database struct {
+	Messages map[TPCANMsgID]*DBCMessage // Message definitions by identifier
+}
+
+// Definition of a message in a DBC file
+type DBCMessage struct {
+	ID       TPCANMsgID  // 11/29-bit message identifier
+	Extended bool        // Message is an extended frame (29-bit identifier)
+	Name     string      // Name of the message
+	DLC      uint8       // Data Length Code of the message
+	Signals  []DBCSignal // Signals contained in the message
+}
+
+// Definition of a signal in a DBC file
+type DBCSignal struct {
+	Name        string  // Name of the signal
+	StartBit    uint    // Start bit as defined in the DBC file (LSB for little endian, MSB for big endian)
+	Length      uint    // Length of the signal in bits
+	BigEndian   bool    // Byte order is Motorola (big endian) if set, Intel (little endian) otherwise
+	Signed      bool    // Raw value is a two's complement signed value
+	Factor      float64 // Scale applied to the raw value
+	Offset      float64 // Offset added to the scaled value
+	Min         float64 // Minimum physical value
+	Max         float64 // Maximum physical value
+	Unit        string  // Unit of the physical value
+	Multiplexor bool    // Signal selects which multiplexed signals are present
+	Multiplexed bool    // Signal is only present if the multiplexor has the value MuxValue
+	MuxValue    uint64  // Multiplexor value for which the signal is present
+}
+
+// Loads a DBC file
+// path: Path of the DBC file
+// Note: Only message and signal definitions are evaluated, all other sections are ignored
+func LoadDBC(path string) (*Database, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseDBC(file)
+}
+
+// Parses message and signal definitions of a DBC file
+func ParseDBC(r io.Reader) (*Database, error) {
+	db := &Database{Messages: make(map[TPCANMsgID]*DBCMessage)}
+	var current *DBCMessage
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case strings.HasPrefix(line, "BO_ "):
+			match := dbcMessageRegex.FindStringSubmatch(line)
+			if match == nil {
+				return nil, fmt.Errorf("invalid message definition in line %v: %q", lineNumber, line)
+			}
+			rawID, _ := strconv.ParseUint(match[1], 10, 32)
+			dlc, _ := strconv.ParseUint(match[3], 10, 8)
+			current = &DBCMessage{
+				ID:       TPCANMsgID(rawID &^ dbcExtendedFlag),
+				Extended: rawID&dbcExtendedFlag != 0,
+				Name:     match[2],
+				DLC:      uint8(dlc)}
+			db.Messages[current.ID] = current
+
+		case strings.HasPrefix(line, "SG_ "):
+			if current == nil {
+				return nil, fmt.Errorf("signal definition without message in line %v", lineNumber)
+			}
+			signal, err := parseDBCSignal(line)
+			if err != nil {
+				return nil, fmt.Errorf("invalid signal definition in line %v: %v", lineNumber, err)
+			}
+			current.Signals = append(current.Signals, signal)
+
+		case line == "":
+			current = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// parses a single SG_ line
+func parseDBCSignal(line string) (DBCSignal, error) {
+	match := dbcSignalRegex.FindStringSubmatch(line)
+	if match == nil {
+		return DBCSignal{}, fmt.Errorf("%q", line)
+	}
+
+	signal := DBCSignal{
+		Name:      match[1],
+		BigEndian: match[5] == "0",
+		Signed:    match[6] == "-",
+		Unit:      match[11]}
+
+	var err error
+	values := []struct {
+		text string
+		dst  *float64
+	}{{match[7], &signal.Factor}, {match[8], &signal.Offset}, {match[9], &signal.Min}, {match[10], &signal.Max}}
+	for _, v := range values {
+		if *v.dst, err = strconv.ParseFloat(strings.TrimSpace(v.text), 64); err != nil {
+			return signal, err
+		}
+	}
+
+	startBit, _ := strconv.ParseUint(match[3], 10, 32)
+	length, _ := strconv.ParseUint(match[4], 10, 32)
+	if length == 0 || length > 64 {
+		return signal, fmt.Errorf("invalid signal length %v of signal %v", length, signal.Name)
+	}
+	signal.StartBit = uint(startBit)
+	signal.Length = uint(length)
+
+	switch {
+	case match[2] == "M":
+		signal.Multiplexor = true
+	case match[2] != "":
+		signal.Multiplexed = true
+		signal.MuxValue, err = strconv.ParseUint(match[2][1:], 10, 64)
+	}
+	return signal, err
+}
+
+// Decodes the signals of a received message into physical values
+// msg: The message to decode
+// Note: Multiplexed signals are only decoded if the multiplexor selects them
+func (db *Database) Decode(msg *TPCANMsg) (map[string]float64, error) {
+	def, ok := db.Messages[msg.ID]
+	if !ok || def.Extended != (msg.MsgType&PCAN_MESSAGE_EXTENDED != 0) {
+		return nil, fmt.Errorf("message 0x%X is not defined in database", msg.ID)
+	}
+	if msg.DLC < def.DLC {
+		return nil, fmt.Errorf("message %v has a DLC of %v but %v is defined", def.Name, msg.DLC, def.DLC)
+	}
+
+	// evaluate multiplexor first
+	var muxValue uint64
+	for _, signal := range def.Signals {
+		if signal.Multiplexor {
+			muxValue, _ = extractSignal(msg.Data[:], signal.StartBit, signal.Length, signal.BigEndian)
+		}
+	}
+
+	values := make(map[string]float64, len(def.Signals))
+	for _, signal := range def.Signals {
+		if signal.Multiplexed && signal.MuxValue != muxValue {
+			continue
+		}
+		raw, ok := extractSignal(msg.Data[:], signal.StartBit, signal.Length, signal.BigEndian)
+		if !ok {
+			return nil, fmt.Errorf("signal %v exceeds the data of message %v", signal.Name, def.Name)
+		}
+		values[signal.Name] = signal.physical(raw)
+	}
+	return values, nil
+}
+
+// Encodes physical signal values into a message defined in the database
+// msgName: Name of the message as defined in the DBC file
+// signals: Physical values by signal name, signals not given are set to a raw value of zero
+// Note: Values are clamped to the defined signal range. Multiplexed signals are only allowed if the multiplexor selects them.
+// Note: Returns an error if a raw value does not fit into the bits of its signal, e.g. if the signal has no range defined
+func (db *Database) Encode(msgName string, signals map[string]float64) (TPCANMsg, error) {
+	def := db.MessageByName(msgName)
+	if def == nil {
+		return TPCANMsg{}, fmt.Errorf("message %v is not defined in database", msgName)
+	}
+	if def.DLC > LENGTH_DATA_CAN_MESSAGE {
+		return TPCANMsg{}, fmt.Errorf("message %v with a DLC of %v is no classic CAN message", def.Name, def.DLC)
+	}
+
+	msg := TPCANMsg{ID: def.ID, MsgType: msgTypeFor(def.Extended), DLC: def.DLC}
+
+	// evaluate multiplexor first
+	var muxValue uint64
+	for _, signal := range def.Signals {
+		if signal.Multiplexor {
+			raw, err := signal.raw(signals[signal.Name])
+			if err != nil {
+				return TPCANMsg{}, err
+			}
+			muxValue = raw
+		}
+	}
+
+	known := make(map[string]bool, len(def.Signals))
+	for _, signal := range def.Signals {
+		value, given := signals[signal.Name]
+		known[signal.Name] = true
+		if !given {
+			continue
+		}
+		if signal.Multiplexed && signal.MuxValue != muxValue {
+			return TPCANMsg{}, fmt.Errorf("signal %v is not selected by multiplexor value %v", signal.Name, muxValue)
+		}
+		raw, err := signal.raw(value)
+		if err != nil {
+			return TPCANMsg{}, err
+		}
+		if !insertSignal(msg.Data[:], signal.StartBit, signal.Length, signal.BigEndian, raw) {
+			return TPCANMsg{}, fmt.Errorf("signal %v exceeds the data of message %v", signal.Name, def.Name)
+		}
+	}
+	for name := range signals {
+		if !known[name] {
+			return TPCANMsg{}, fmt.Errorf("signal %v is not defined in message %v", name, def.Name)
+		}
+	}
+	return msg, nil
+}
+
+// Returns the message definition with the given name, nil if not defined
+func (db *Database) MessageByName(name string) *DBCMessage {
+	for _, msg := range db.Messages {
+		if msg.Name == name {
+			return msg
+		}
+	}
+	return nil
+}
+
+// converts a physical value into its raw signal value, clamped to the defined range
+// Note: Returns an error if the raw value is not representable with the length of the signal
+func (s *DBCSignal) raw(value float64) (uint64, error) {
+	if s.Min < s.Max {
+		value = math.Min(math.Max(value, s.Min), s.Max)
+	}
+	factor := s.Factor
+	if factor == 0 {
+		factor = 1
+	}
+	raw := math.Round((value - s.Offset) / factor)
+
+	// limits of the raw value as float, exact for every length as they are powers of two
+	var low, high float64
+	if s.Signed {
+		low, high = -math.Ldexp(1, int(s.Length)-1), math.Ldexp(1, int(s.Length)-1)
+	} else {
+		low, high = 0, math.Ldexp(1, int(s.Length))
+	}
+	if math.IsNaN(raw) || raw < low || raw >= high {
+		return 0, fmt.Errorf("value %v of signal %v exceeds the raw range of %v bits", value, s.Name, s.Length)
+	}
+
+	if s.Signed {
+		mask := ^uint64(0)
+		if s.Length < 64 {
+			mask = 1<<s.Length - 1
+		}
+		return uint64(int64(raw)) & mask, nil
+	}
+	return uint64(raw), nil
+}
+
+// converts a raw signal value into its physical value
+func (s *DBCSignal) physical(raw uint64) float64 {
+	if s.Signed && s.Length < 64 && raw&(1<<(s.Length-1)) != 0 {
+		raw |= ^uint64(0) << s.Length // sign extension
+	}
+	if s.Signed {
+		return float64(int64(raw))*s.Factor + s.Offset
+	}
+	return float64(raw)*s.Factor + s.Offset
+}
+
+// Extracts the raw value of a signal from message data using the DBC bit numbering
+// data: Message data, e.g. msg.Data[:msg.DLC]
+// startBit: DBC start bit, the LSB for Intel and the MSB for Motorola byte order
+// length: Amount of bits of the signal (1..64)
+// motorola: Byte order is Motorola (big endian, "@0" in a DBC file), otherwise Intel (little endian, "@1")
+// Note: Bit n is bit n%8 of byte n/8. Motorola signals continue with the next lower bit and wrap from bit 0 of a
+// byte to bit 7 of the following byte, e.g. start bit 7 with length 16 reads data[0] followed by data[1].
+func ExtractSignal(data []byte, startBit int, length int, motorola bool) (uint64, error) {
+	if err := checkSignalLayout(startBit, length); err != nil {
+		return 0, err
+	}
+	value, ok := extractSignal(data, uint(startBit), uint(length), motorola)
+	if !ok {
+		return 0, fmt.Errorf("signal at start bit %v with %v bits exceeds the %v data bytes", startBit, length, len(data))
+	}
+	return value, nil
+}
+
+// Inserts the raw value of a signal into message data using the DBC bit numbering, see ExtractSignal()
+// data: Message data the signal is written to, bits outside of the signal are not changed
+// value: Raw value, must fit into length bits
+// Note: The data is not changed if an error is returned
+func InsertSignal(data []byte, startBit int, length int, motorola bool, value uint64) error {
+	if err := checkSignalLayout(startBit, length); err != nil {
+		return err
+	}
+	if length < 64 && value>>length != 0 {
+		return fmt.Errorf("value 0x%X does not fit into %v bits", value, length)
+	}
+	// check the range first, a Motorola signal is only known to exceed the data while it is traversed
+	if _, ok := extractSignal(data, uint(startBit), uint(length), motorola); !ok {
+		return fmt.Errorf("signal at start bit %v with %v bits exceeds the %v data bytes", startBit, length, len(data))
+	}
+	insertSignal(data, uint(startBit), uint(length), motorola, value)
+	return nil
+}
+
+// checks the start bit and length of a signal independent of the data
+func checkSignalLayout(startBit int, length int) error {
+	if startBit < 0 {
+		return fmt.Errorf("start bit %v is negative", startBit)
+	}
+	if length < 1 || length > 64 {
+		return fmt.Errorf("signal length of %v bits is not in range 1..64", length)
+	}
+	return nil
+}
+
+// extracts a raw signal value from the message data using the DBC bit numbering
+// Note: Returns false if the signal exceeds the message data
+func extractSignal(data []byte, startBit uint, length uint, bigEndian bool) (uint64, bool) {
+	var value uint64
+	if bigEndian {
+		// start bit is the MSB, bits are traversed in sawtooth order
+		pos := startBit
+		for i := uint(0); i < length; i++ {
+			if pos/8 >= uint(len(data)) {
+				return 0, false
+			}
+			value = value<<1 | uint64(data[pos/8]>>(pos%8)&1)
+			if pos%8 == 0 {
+				pos += 15
+			} else {
+				pos--
+			}
+		}
+		return value, true
+	}
+
+	// start bit is the LSB
+	if startBit+length > uint(len(data))*8 {
+		return 0, false
+	}
+	for i := uint(0); i < length; i++ {
+		pos := startBit + i
+		value |= uint64(data[pos/8]>>(pos%8)&1) << i
+	}
+	return value, true
+}
+
+// inserts a raw signal value into the message data using the DBC bit numbering
+// Note: Returns false if the signal exceeds the message data
+func insertSignal(data []byte, startBit uint, length uint, bigEndian bool, value uint64) bool {
+	if bigEndian {
+		// start bit is the MSB, bits are traversed in sawtooth order
+		pos := startBit
+		for i := uint(0); i < length; i++ {
+			if pos/8 >= uint(len(data)) {
+				return false
+			}
+			bit := byte(value>>(length-1-i)) & 1
+			data[pos/8] = data[pos/8]&^(1<<(pos%8)) | bit<<(pos%8)
+			if pos%8 == 0 {
+				pos += 15
+			} else {
+				pos--
+			}
+		}
+		return true
+	}
+
+	// start bit is the LSB
+	if startBit+length > uint(len(data))*8 {
+		return false
+	}
+	for i := uint(0); i < length; i++ {
+		pos := startBit + i
+		bit := byte(value>>i) & 1
+		data[pos/8] = data[pos/8]&^(1<<(pos%8)) | bit<<(pos%8)
+	}
+	return true
+}
@@ -0,0 +1,198 @@
+package pcan
+
+import (
+	"bytes"
+	"testing"
+)
+
+/* Tests of the DBC signal encoding. */
+
+// returns a database with a single message containing the given signals
+func testDatabase(signals ...DBCSignal) *Database {
+	return &Database{Messages: map[TPCANMsgID]*DBCMessage{
+		0x100: {ID: 0x100, Name: "Test", DLC: 8, Signals: signals},
+	}}
+}
+
+func TestEncodeRawRange(t *testing.T) {
+	unsigned8 := DBCSignal{Name: "U8", StartBit: 0, Length: 8, Factor: 1}
+	signed8 := DBCSignal{Name: "S8", StartBit: 8, Length: 8, Signed: true, Factor: 1}
+	scaled := DBCSignal{Name: "Scaled", StartBit: 16, Length: 4, Factor: 0.5, Offset: 10}
+	clamped := DBCSignal{Name: "Clamped", StartBit: 24, Length: 8, Factor: 1, Min: 0, Max: 100}
+
+	tests := []struct {
+		name    string
+		signal  DBCSignal
+		value   float64
+		byteIdx int
+		want    byte
+		wantErr bool
+	}{
+		{"unsigned max", unsigned8, 255, 0, 0xFF, false},
+		{"unsigned overflow", unsigned8, 256, 0, 0, true},
+		{"unsigned negative", unsigned8, -1, 0, 0, true},
+		{"signed min", signed8, -128, 1, 0x80, false},
+		{"signed minus one", signed8, -1, 1, 0xFF, false},
+		{"signed max", signed8, 127, 1, 0x7F, false},
+		{"signed overflow", signed8, 128, 1, 0, true},
+		{"signed underflow", signed8, -129, 1, 0, true},
+		{"scaled max", scaled, 17.5, 2, 0x0F, false},
+		{"scaled overflow", scaled, 18, 2, 0, true},
+		{"scaled below offset", scaled, 9, 2, 0, true},
+		{"clamped to range", clamped, 1000, 3, 100, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			msg, err := testDatabase(test.signal).Encode("Test", map[string]float64{test.signal.Name: test.value})
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("Encode(%v) = % X, want an error", test.value, msg.Data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Encode(%v) = %v", test.value, err)
+			}
+			if msg.Data[test.byteIdx] != test.want {
+				t.Errorf("Encode(%v) = % X, want %#02x in byte %v", test.value, msg.Data, test.want, test.byteIdx)
+			}
+		})
+	}
+}
+
+func TestExtractSignal(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		startBit int
+		length   int
+		motorola bool
+		want     uint64
+		wantErr  bool
+	}{
+		{"intel 16 bit", []byte{0x12, 0x34}, 0, 16, false, 0x3412, false},
+		{"motorola 16 bit", []byte{0x12, 0x34}, 7, 16, true, 0x1234, false},
+		{"intel across bytes", []byte{0xA0, 0x0B}, 4, 8, false, 0xBA, false},
+		{"motorola across bytes", []byte{0x0A, 0xB0}, 3, 8, true, 0xAB, false},
+		{"motorola across three bytes", []byte{0x00, 0x15, 0x78}, 12, 12, true, 0xABC, false},
+		{"intel single bit", []byte{0x00, 0x20}, 13, 1, false, 1, false},
+		{"motorola single bit", []byte{0x00, 0x20}, 13, 1, true, 1, false},
+		{"intel 64 bit", []byte{1, 2, 3, 4, 5, 6, 7, 8}, 0, 64, false, 0x0807060504030201, false},
+		{"motorola 64 bit", []byte{1, 2, 3, 4, 5, 6, 7, 8}, 7, 64, true, 0x0102030405060708, false},
+		{"intel beyond data", []byte{1, 2, 3, 4, 5, 6, 7, 8}, 60, 8, false, 0, true},
+		{"motorola beyond data", []byte{0x12}, 7, 16, true, 0, true},
+		{"motorola wraps beyond data", []byte{0x12}, 0, 2, true, 0, true},
+		{"negative start bit", []byte{0x12}, -1, 1, false, 0, true},
+		{"zero length", []byte{0x12}, 0, 0, false, 0, true},
+		{"length above 64", make([]byte, 9), 0, 65, false, 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ExtractSignal(test.data, test.startBit, test.length, test.motorola)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("ExtractSignal() = 0x%X, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExtractSignal() = %v", err)
+			}
+			if got != test.want {
+				t.Errorf("ExtractSignal() = 0x%X, want 0x%X", got, test.want)
+			}
+		})
+	}
+}
+
+func TestInsertSignal(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		startBit int
+		length   int
+		motorola bool
+		value    uint64
+		want     []byte
+		wantErr  bool
+	}{
+		{"intel 16 bit", []byte{0, 0}, 0, 16, false, 0x3412, []byte{0x12, 0x34}, false},
+		{"motorola 16 bit", []byte{0, 0}, 7, 16, true, 0x1234, []byte{0x12, 0x34}, false},
+		{"intel across bytes", []byte{0, 0}, 4, 8, false, 0xBA, []byte{0xA0, 0x0B}, false},
+		{"motorola across bytes", []byte{0, 0}, 3, 8, true, 0xAB, []byte{0x0A, 0xB0}, false},
+		{"intel keeps other bits", []byte{0xFF, 0xFF}, 4, 8, false, 0x00, []byte{0x0F, 0xF0}, false},
+		{"motorola keeps other bits", []byte{0xFF, 0xFF}, 3, 8, true, 0x00, []byte{0xF0, 0x0F}, false},
+		{"motorola across three bytes", []byte{0, 0, 0}, 12, 12, true, 0xABC, []byte{0x00, 0x15, 0x78}, false},
+		{"value too large", []byte{0, 0}, 0, 8, false, 0x100, []byte{0, 0}, true},
+		{"intel beyond data", []byte{0, 0}, 12, 8, false, 0x01, []byte{0, 0}, true},
+		{"motorola beyond data", []byte{0, 0}, 11, 16, true, 0x01, []byte{0, 0}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := InsertSignal(test.data, test.startBit, test.length, test.motorola, test.value)
+			if test.wantErr != (err != nil) {
+				t.Fatalf("InsertSignal() = %v, want an error %v", err, test.wantErr)
+			}
+			if !bytes.Equal(test.data, test.want) {
+				t.Errorf("InsertSignal() data = % X, want % X", test.data, test.want)
+			}
+		})
+	}
+}
+
+func TestInsertExtractSignalRoundTrip(t *testing.T) {
+	layouts := []struct {
+		startBit int
+		length   int
+		motorola bool
+	}{
+		{0, 1, false}, {5, 7, false}, {12, 20, false}, {0, 64, false},
+		{0, 1, true}, {3, 7, true}, {12, 20, true}, {7, 64, true},
+	}
+	for _, layout := range layouts {
+		mask := ^uint64(0) >> (64 - layout.length)
+		for _, value := range []uint64{0, 1, 0x5A5A5A5A5A5A5A5A & mask, mask} {
+			data := bytes.Repeat([]byte{0xA5}, 8)
+			if err := InsertSignal(data, layout.startBit, layout.length, layout.motorola, value); err != nil {
+				t.Fatalf("InsertSignal(%+v, 0x%X) = %v", layout, value, err)
+			}
+			got, err := ExtractSignal(data, layout.startBit, layout.length, layout.motorola)
+			if err != nil || got != value {
+				t.Errorf("ExtractSignal(InsertSignal(%+v, 0x%X)) = 0x%X, %v", layout, value, got, err)
+			}
+		}
+	}
+}
+
+func TestSignedSignalRoundTrip(t *testing.T) {
+	intel := DBCSignal{Name: "Intel", StartBit: 4, Length: 12, Signed: true, Factor: 1}
+	motorola := DBCSignal{Name: "Motorola", StartBit: 27, Length: 12, BigEndian: true, Signed: true, Factor: 0.5}
+
+	tests := []struct {
+		signal  DBCSignal
+		value   float64
+		wantRaw uint64
+	}{
+		{intel, -1, 0xFFF},
+		{intel, -2048, 0x800},
+		{intel, 2047, 0x7FF},
+		{motorola, -0.5, 0xFFF},
+		{motorola, -1024, 0x800},
+		{motorola, 100, 0x0C8},
+	}
+	for _, test := range tests {
+		db := testDatabase(test.signal)
+		msg, err := db.Encode("Test", map[string]float64{test.signal.Name: test.value})
+		if err != nil {
+			t.Fatalf("Encode(%v) = %v", test.value, err)
+		}
+		raw, err := ExtractSignal(msg.Data[:], int(test.signal.StartBit), int(test.signal.Length), test.signal.BigEndian)
+		if err != nil || raw != test.wantRaw {
+			t.Errorf("raw value of %v = 0x%X, %v, want 0x%X", test.value, raw, err, test.wantRaw)
+		}
+		values, err := db.Decode(&msg)
+		if err != nil || values[test.signal.Name] != test.value {
+			t.Errorf("Decode(Encode(%v)) = %v, %v", test.value, values, err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package pcan
+
+import (
+	"fmt"
+	"strings"
+)
+
+/* Collection of channel information for diagnostics and bug reports. */
+
+// Information about a channel, its device and the driver gathered by Diagnostics()
+// Note: Fields of information not available for the device are left zero and explained in Notes
+type DiagnosticInfo struct {
+	Handle          TPCANHandle     // Handle of the channel
+	APIVersion      string          // Version of the PCAN-Basic api (PCAN_API_VERSION)
+	ChannelVersion  string          // Version of the channel driver (PCAN_CHANNEL_VERSION)
+	HardwareName    string          // Name of the device (PCAN_HARDWARE_NAME)
+	PartNumber      string          // Part number of the device (PCAN_DEVICE_PART_NUMBER)
+	FirmwareVersion string          // Firmware version of the device (PCAN_FIRMWARE_VERSION)
+	Features        ChannelFeatures // Capabilities of the device (PCAN_CHANNEL_FEATURES)
+	Status          TPCANStatus     // Current channel status
+	BusState        BusState        // Error state derived from Status
+	CountersValid   bool            // Set if REC and TEC could be read, see ErrorCounters()
+	REC             uint8           // Receive error counter
+	TEC             uint8           // Transmit error counter
+	Notes           []string        // Information which could not be read and why
+}
+
+// Gathers the information about the channel, its device and the driver
+// Note: Information not available for the device is noted in the result, an error is only returned if the
+// channel status can not be read at all
+func (p *TPCANBus) Diagnostics() (DiagnosticInfo, error) {
+	info := DiagnosticInfo{Handle: p.Handle}
+	note := func(what string, status TPCANStatus, err error) bool {
+		if err != nil {
+			info.Notes = append(info.Notes, fmt.Sprintf("%v: %v", what, err))
+			return false
+		}
+		if status != PCAN_ERROR_OK {
+			info.Notes = append(info.Notes, fmt.Sprintf("%v: status 0x%X", what, status))
+			return false
+		}
+		return true
+	}
+
+	texts := []struct {
+		what  string
+		field *string
+		read  func() (TPCANStatus, string, error)
+	}{
+		{"api version", &info.APIVersion, func() (TPCANStatus, string, error) { return getHandleStringValue(PCAN_NONEBUS, PCAN_API_VERSION) }},
+		{"channel version", &info.ChannelVersion, func() (TPCANStatus, string, error) { return p.getStringValue(PCAN_CHANNEL_VERSION) }},
+		{"hardware name", &info.HardwareName, func() (TPCANStatus, string, error) { return p.getStringValue(PCAN_HARDWARE_NAME) }},
+		{"part number", &info.PartNumber, func() (TPCANStatus, string, error) { return p.getStringValue(PCAN_DEVICE_PART_NUMBER) }},
+		{"firmware version", &info.FirmwareVersion, func() (TPCANStatus, string, error) { return p.getStringValue(PCAN_FIRMWARE_VERSION) }},
+	}
+	for _, text := range texts {
+		if status, value, err := text.read(); note(text.what, status, err) {
+			*text.field = value
+		}
+	}
+
+	if status, features, err := p.Features(); note("channel features", status, err) {
+		info.Features = features
+	}
+	if status, rec, tec, err := p.ErrorCounters(); note("error counters", status, err) {
+		info.CountersValid, info.REC, info.TEC = true, rec, tec
+	}
+
+	status, state, err := p.GetBusState()
+	if err != nil {
+		return info, err
+	}
+	info.Status, info.BusState = status, state
+	return info, nil
+}
+
+// Formats the information as text with one value per line, e.g. for attaching it to a bug report
+func (d DiagnosticInfo) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Handle:           0x%X\n", d.Handle)
+	fmt.Fprintf(&sb, "API version:      %v\n", d.APIVersion)
+	fmt.Fprintf(&sb, "Channel version:  %v\n", d.ChannelVersion)
+	fmt.Fprintf(&sb, "Hardware name:    %v\n", d.HardwareName)
+	fmt.Fprintf(&sb, "Part number:      %v\n", d.PartNumber)
+	fmt.Fprintf(&sb, "Firmware version: %v\n", d.FirmwareVersion)
+	fmt.Fprintf(&sb, "Features:         FD=%v Delay=%v IO=%v (0x%X)\n", d.Features.FDCapable, d.Features.DelayCapable, d.Features.IOCapable, d.Features.Raw)
+	fmt.Fprintf(&sb, "Status:           0x%X\n", d.Status)
+	fmt.Fprintf(&sb, "Bus state:        %v\n", d.BusState)
+	if d.CountersValid {
+		fmt.Fprintf(&sb, "Error counters:   REC=%v TEC=%v\n", d.REC, d.TEC)
+	} else {
+		sb.WriteString("Error counters:   not available\n")
+	}
+	for _, n := range d.Notes {
+		fmt.Fprintf(&sb, "Note:             %v\n", n)
+	}
+	return sb.String()
+}
@@ -0,0 +1,92 @@
+package pcan
+
+import (
+	"context"
+)
+
+/* Dispatching of received messages to handlers by message type. */
+
+// Callback receiving a message and its timestamp
+type MsgHandler func(msg *TPCANMsg, timestamp *TPCANTimestamp)
+
+// Handlers for the different message types, all handlers are optional
+type TypeHandlers struct {
+	OnData   MsgHandler // Standard and extended data frames
+	OnError  MsgHandler // Error frames (PCAN_MESSAGE_ERRFRAME)
+	OnStatus MsgHandler // PCAN status messages (PCAN_MESSAGE_STATUS)
+	OnEcho   MsgHandler // Echo frames of sent messages (PCAN_MESSAGE_ECHO)
+	OnRTR    MsgHandler // Remote transmission request frames (PCAN_MESSAGE_RTR)
+	Default  MsgHandler // Messages without a matching handler
+}
+
+// Reads messages from the bus and dispatches them by type until ctx is cancelled or a read error occurs
+// handlers: Handlers called for the different message types
+// Note: A message is classified by its first matching type in the order status, error, echo, RTR, data
+func DispatchByType(ctx context.Context, p *TPCANBus, handlers TypeHandlers) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		_, msg, timestamp, err := p.ReadWithTimeout(monitorReadTimeout)
+		if err != nil {
+			return err
+		}
+		if msg == nil {
+			continue
+		}
+		if handler := handlers.handlerFor(msg.MsgType); handler != nil {
+			handler(msg, timestamp)
+		}
+	}
+}
+
+// returns the handler matching the message type, the default handler if none matches
+func (h *TypeHandlers) handlerFor(msgType TPCANMessageType) MsgHandler {
+	var handler MsgHandler
+	switch ClassifyMsgType(msgType) {
+	case FrameKindStatus:
+		handler = h.OnStatus
+	case FrameKindError:
+		handler = h.OnError
+	case FrameKindEcho:
+		handler = h.OnEcho
+	case FrameKindRTR:
+		handler = h.OnRTR
+	default:
+		handler = h.OnData
+	}
+	if handler == nil {
+		return h.Default
+	}
+	return handler
+}
+
+// Category of a received message
+type FrameKind int
+
+const (
+	FrameKindData   FrameKind = iota // Standard or extended data frame
+	FrameKindStatus                  // PCAN status message (PCAN_MESSAGE_STATUS)
+	FrameKindError                   // Error frame (PCAN_MESSAGE_ERRFRAME)
+	FrameKindEcho                    // Echo frame of a sent message (PCAN_MESSAGE_ECHO)
+	FrameKindRTR                     // Remote transmission request frame (PCAN_MESSAGE_RTR)
+)
+
+// Returns the category of a message by its first matching type in the order status, error, echo, RTR, data
+func ClassifyMsgType(msgType TPCANMessageType) FrameKind {
+	switch {
+	case msgType&PCAN_MESSAGE_STATUS != 0:
+		return FrameKindStatus
+	case msgType&PCAN_MESSAGE_ERRFRAME != 0:
+		return FrameKindError
+	case msgType&PCAN_MESSAGE_ECHO != 0:
+		return FrameKindEcho
+	case msgType&PCAN_MESSAGE_RTR != 0:
+		return FrameKindRTR
+	default:
+		return FrameKindData
+	}
+}
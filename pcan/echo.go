@@ -0,0 +1,142 @@
+package pcan
+
+import (
+	"bytes"
+)
+
+/* Correlation of echo frames with the transmissions they confirm. */
+
+const maxPendingEchoes = 4096 // maximum amount of transmissions waiting for their echo, the oldest ones are dropped beyond
+
+// transmission waiting for its echo
+type pendingEcho struct {
+	msg       TPCANMsg
+	marker    uint32
+	confirmed chan struct{} // closed when the echo is read, nil for transmissions of WriteWithMarker()
+	echoRead  bool          // echo of a transmission of WriteWithMarker() was read and waits for EchoMarker()
+}
+
+// message read while WriteConfirmed() waited for an echo, returned again by Read()
+type unreadFrame struct {
+	status    TPCANStatus
+	msg       TPCANMsg
+	timestamp TPCANTimestamp
+}
+
+// Transmits a CAN message and remembers a marker to identify its echo frame with EchoMarker()
+// msg: A Message struct with the message to be sent
+// marker: Caller defined value returned by EchoMarker() for the echo of this message
+// Note: The PCAN-Basic driver has no transmit markers for any hardware, so echoes are matched by their content
+// in transmission order. This is exact even for identical payloads as echoes are received in the order the messages
+// were sent. Echo frames must be allowed with SetAllowEchoFrames(true), otherwise markers are never returned.
+func (p *TPCANBus) WriteWithMarker(msg *TPCANMsg, marker uint32) (TPCANStatus, error) {
+	// registered before writing, as the echo may be read by another goroutine before Write returns
+	p.addPendingEcho(pendingEcho{msg: *msg, marker: marker})
+
+	status, err := p.Write(msg)
+	if status != PCAN_ERROR_OK || err != nil {
+		p.echoMutex.Lock()
+		for i := len(p.pendingEchoes) - 1; i >= 0; i-- {
+			if p.pendingEchoes[i].marker == marker && echoMatches(&p.pendingEchoes[i].msg, msg) {
+				p.pendingEchoes = append(p.pendingEchoes[:i], p.pendingEchoes[i+1:]...)
+				break
+			}
+		}
+		p.echoMutex.Unlock()
+	}
+	return status, err
+}
+
+// Returns the marker of the transmission confirmed by a received echo frame
+// echo: Message read from the bus, only echo frames (PCAN_MESSAGE_ECHO) are matched
+// Note: A found marker is consumed, so it is returned only once
+func (p *TPCANBus) EchoMarker(echo *TPCANMsg) (uint32, bool) {
+	if echo.MsgType&PCAN_MESSAGE_ECHO == 0 {
+		return 0, false
+	}
+
+	p.echoMutex.Lock()
+	defer p.echoMutex.Unlock()
+	for i := range p.pendingEchoes {
+		if p.pendingEchoes[i].confirmed == nil && echoMatches(&p.pendingEchoes[i].msg, echo) {
+			marker := p.pendingEchoes[i].marker
+			p.pendingEchoes = append(p.pendingEchoes[:i], p.pendingEchoes[i+1:]...)
+			return marker, true
+		}
+	}
+	return 0, false
+}
+
+// checks if an echo frame has the content of a sent message
+func echoMatches(sent *TPCANMsg, echo *TPCANMsg) bool {
+	const frameFlags = PCAN_MESSAGE_EXTENDED | PCAN_MESSAGE_RTR
+	if sent.ID != echo.ID || sent.DLC != echo.DLC || sent.MsgType&frameFlags != echo.MsgType&frameFlags {
+		return false
+	}
+	dataLen := sent.dataLen()
+	return bytes.Equal(sent.Data[:dataLen], echo.Data[:dataLen])
+}
+
+// registers a transmission waiting for its echo, the oldest one is dropped if too many are waiting
+func (p *TPCANBus) addPendingEcho(echo pendingEcho) {
+	p.echoMutex.Lock()
+	defer p.echoMutex.Unlock()
+	if len(p.pendingEchoes) >= maxPendingEchoes {
+		p.pendingEchoes = p.pendingEchoes[1:]
+	}
+	p.pendingEchoes = append(p.pendingEchoes, echo)
+}
+
+// confirms the transmission of WriteConfirmed() waiting for a received echo frame
+// Note: Echoes are received in transmission order, so the echo belongs to the oldest transmission with its content
+// whose echo was not read yet. If that one was sent by WriteWithMarker(), it is left for EchoMarker().
+func (p *TPCANBus) confirmEcho(echo *TPCANMsg) {
+	p.echoMutex.Lock()
+	defer p.echoMutex.Unlock()
+	for i := range p.pendingEchoes {
+		if p.pendingEchoes[i].echoRead || !echoMatches(&p.pendingEchoes[i].msg, echo) {
+			continue
+		}
+		if p.pendingEchoes[i].confirmed != nil {
+			close(p.pendingEchoes[i].confirmed)
+			p.pendingEchoes = append(p.pendingEchoes[:i], p.pendingEchoes[i+1:]...)
+		} else {
+			p.pendingEchoes[i].echoRead = true
+		}
+		return
+	}
+}
+
+// removes the transmission of WriteConfirmed() if its echo was not read
+func (p *TPCANBus) removeConfirmation(confirmed chan struct{}) {
+	p.echoMutex.Lock()
+	defer p.echoMutex.Unlock()
+	for i := range p.pendingEchoes {
+		if p.pendingEchoes[i].confirmed == confirmed {
+			p.pendingEchoes = append(p.pendingEchoes[:i], p.pendingEchoes[i+1:]...)
+			return
+		}
+	}
+}
+
+// keeps a message read while waiting for an echo so the next Read() returns it, the oldest one is dropped if too many are kept
+func (p *TPCANBus) pushUnread(frame unreadFrame) {
+	p.echoMutex.Lock()
+	defer p.echoMutex.Unlock()
+	if len(p.unread) >= maxPendingEchoes {
+		p.unread = p.unread[1:]
+	}
+	p.unread = append(p.unread, frame)
+}
+
+// returns the oldest message kept by pushUnread()
+func (p *TPCANBus) popUnread() (unreadFrame, bool) {
+	p.echoMutex.Lock()
+	defer p.echoMutex.Unlock()
+	if len(p.unread) == 0 {
+		return unreadFrame{}, false
+	}
+	frame := p.unread[0]
+	p.unread = p.unread[1:]
+	return frame, true
+}
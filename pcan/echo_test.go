@@ -0,0 +1,120 @@
+package pcan
+
+import (
+	"testing"
+	"time"
+)
+
+/* Tests of the confirmation of transmissions by their echo frames. */
+
+// stubs the driver of a channel with echo frames allowed or not, returns the amount of status requests
+func stubEchoChannel(t *testing.T, echoAllowed bool) *int {
+	t.Helper()
+	stubProc(t, &pHandleGetValue, func(a ...uintptr) (uintptr, uintptr, error) {
+		if TPCANParameter(a[1]) != PCAN_ALLOW_ECHO_FRAMES {
+			return uintptr(PCAN_ERROR_ILLPARAMTYPE), 0, nil
+		}
+		value := PCAN_PARAMETER_OFF
+		if echoAllowed {
+			value = PCAN_PARAMETER_ON
+		}
+		*argPtr[TPCANParameterValue](a[2]) = value
+		return uintptr(PCAN_ERROR_OK), 0, nil
+	})
+	stubProc(t, &pHandleWrite, func(a ...uintptr) (uintptr, uintptr, error) { return uintptr(PCAN_ERROR_OK), 0, nil })
+	statusRequests := new(int)
+	stubProc(t, &pHandleGetStatus, func(a ...uintptr) (uintptr, uintptr, error) {
+		*statusRequests++
+		return uintptr(PCAN_ERROR_OK), 0, nil
+	})
+	return statusRequests
+}
+
+// returns the echo frame of a sent message
+func echoFrame(frame stubFrame) stubFrame {
+	frame.msg.MsgType |= PCAN_MESSAGE_ECHO
+	return frame
+}
+
+func TestWriteConfirmedByEcho(t *testing.T) {
+	stubEchoChannel(t, true)
+	sent := dataFrame(0x123, 1, 2)
+	stubRead(t, dataFrame(0x200, 9), echoFrame(dataFrame(0x123, 1, 3)), echoFrame(sent))
+
+	bus := &TPCANBus{Handle: PCAN_USBBUS1}
+	start := time.Now()
+	status, err := bus.WriteConfirmed(&sent.msg, time.Minute)
+	if status != PCAN_ERROR_OK || err != nil {
+		t.Fatalf("WriteConfirmed() = %v, %v", status, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("WriteConfirmed() took %v, want it to return on the echo", elapsed)
+	}
+	if len(bus.pendingEchoes) != 0 {
+		t.Errorf("%v transmissions still wait for their echo", len(bus.pendingEchoes))
+	}
+
+	// the messages read while waiting are not lost
+	want := []stubFrame{dataFrame(0x200, 9), echoFrame(dataFrame(0x123, 1, 3)), echoFrame(sent)}
+	for _, frame := range want {
+		_, msg, _, err := bus.Read()
+		if err != nil || msg == nil || *msg != frame.msg {
+			t.Errorf("Read() = %+v, %v, want %+v", msg, err, frame.msg)
+		}
+	}
+	if status, msg, _, _ := bus.Read(); status != PCAN_ERROR_QRCVEMPTY || msg != nil {
+		t.Errorf("Read() = %v, %+v, want an empty queue", status, msg)
+	}
+}
+
+func TestWriteConfirmedEchoMissing(t *testing.T) {
+	stubEchoChannel(t, true)
+	stubRead(t, dataFrame(0x200, 9))
+
+	bus := &TPCANBus{Handle: PCAN_USBBUS1}
+	bus.SetPollInterval(time.Millisecond)
+	sent := dataFrame(0x123, 1, 2)
+	if _, err := bus.WriteConfirmed(&sent.msg, 5*time.Millisecond); err == nil {
+		t.Fatal("WriteConfirmed() succeeded without an echo")
+	}
+	if len(bus.pendingEchoes) != 0 {
+		t.Errorf("%v transmissions still wait for their echo", len(bus.pendingEchoes))
+	}
+}
+
+func TestWriteConfirmedLeavesMarkedEcho(t *testing.T) {
+	stubEchoChannel(t, true)
+	sent := dataFrame(0x123, 1, 2)
+	stubRead(t, echoFrame(sent), echoFrame(sent))
+
+	bus := &TPCANBus{Handle: PCAN_USBBUS1}
+	if _, err := bus.WriteWithMarker(&sent.msg, 42); err != nil {
+		t.Fatal(err)
+	}
+	if status, err := bus.WriteConfirmed(&sent.msg, time.Minute); status != PCAN_ERROR_OK || err != nil {
+		t.Fatalf("WriteConfirmed() = %v, %v", status, err)
+	}
+
+	// the first echo belongs to the message sent before
+	_, msg, _, _ := bus.Read()
+	if marker, ok := bus.EchoMarker(msg); !ok || marker != 42 {
+		t.Errorf("EchoMarker() = %v, %v, want 42", marker, ok)
+	}
+}
+
+func TestWriteConfirmedWithoutEcho(t *testing.T) {
+	statusRequests := stubEchoChannel(t, false)
+	stubRead(t, dataFrame(0x200, 9))
+
+	bus := &TPCANBus{Handle: PCAN_USBBUS1}
+	sent := dataFrame(0x123, 1, 2)
+	if status, err := bus.WriteConfirmed(&sent.msg, 0); status != PCAN_ERROR_OK || err != nil {
+		t.Fatalf("WriteConfirmed() = %v, %v", status, err)
+	}
+	if *statusRequests == 0 {
+		t.Error("bus status was not checked without echo frames")
+	}
+	if _, msg, _, _ := bus.Read(); msg == nil || msg.ID != 0x200 {
+		t.Errorf("Read() = %+v, want the message not touched by WriteConfirmed", msg)
+	}
+}
@@ -0,0 +1,120 @@
+package pcan
+
+import "fmt"
+
+/* Decoding of the payload of error frames. */
+
+// Content of an error frame received with PCAN_ALLOW_ERROR_FRAMES enabled
+type ErrorFrameInfo struct {
+	Type      ErrorFrameType // Kind of the detected bus error
+	Direction Direction      // DirectionTx if the error was detected while transmitting, DirectionRx while receiving
+	ECC       uint8          // Raw error code capture register of the CAN controller
+	Location  ErrorLocation  // Frame segment the error was detected in, taken from ECC
+	REC       uint8          // Receive error counter when the error was detected
+	TEC       uint8          // Transmit error counter when the error was detected
+}
+
+// Segment of a CAN frame an error was detected in, as encoded in the lower five bits of the error code capture
+type ErrorLocation uint8
+
+const (
+	ERROR_LOCATION_ID28_21         = ErrorLocation(0x02) // Identifier bits 28 to 21
+	ERROR_LOCATION_SOF             = ErrorLocation(0x03) // Start of frame
+	ERROR_LOCATION_SRTR            = ErrorLocation(0x04) // SRTR bit
+	ERROR_LOCATION_IDE             = ErrorLocation(0x05) // IDE bit
+	ERROR_LOCATION_ID20_18         = ErrorLocation(0x06) // Identifier bits 20 to 18
+	ERROR_LOCATION_ID17_13         = ErrorLocation(0x07) // Identifier bits 17 to 13
+	ERROR_LOCATION_CRC             = ErrorLocation(0x08) // CRC sequence
+	ERROR_LOCATION_R0              = ErrorLocation(0x09) // Reserved bit 0
+	ERROR_LOCATION_DATA            = ErrorLocation(0x0A) // Data field
+	ERROR_LOCATION_DLC             = ErrorLocation(0x0B) // Data length code
+	ERROR_LOCATION_RTR             = ErrorLocation(0x0C) // RTR bit
+	ERROR_LOCATION_R1              = ErrorLocation(0x0D) // Reserved bit 1
+	ERROR_LOCATION_ID4_0           = ErrorLocation(0x0E) // Identifier bits 4 to 0
+	ERROR_LOCATION_ID12_5          = ErrorLocation(0x0F) // Identifier bits 12 to 5
+	ERROR_LOCATION_ACTIVE_FLAG     = ErrorLocation(0x11) // Active error flag
+	ERROR_LOCATION_INTERMISSION    = ErrorLocation(0x12) // Intermission
+	ERROR_LOCATION_DOMINANT_BITS   = ErrorLocation(0x13) // Tolerate dominant bits
+	ERROR_LOCATION_PASSIVE_FLAG    = ErrorLocation(0x16) // Passive error flag
+	ERROR_LOCATION_ERROR_DELIMITER = ErrorLocation(0x17) // Error delimiter
+	ERROR_LOCATION_CRC_DELIMITER   = ErrorLocation(0x18) // CRC delimiter
+	ERROR_LOCATION_ACK_SLOT        = ErrorLocation(0x19) // Acknowledge slot
+	ERROR_LOCATION_EOF             = ErrorLocation(0x1A) // End of frame
+	ERROR_LOCATION_ACK_DELIMITER   = ErrorLocation(0x1B) // Acknowledge delimiter
+	ERROR_LOCATION_OVERLOAD_FLAG   = ErrorLocation(0x1C) // Overload flag
+)
+
+// names of the error locations
+var errorLocationNames = map[ErrorLocation]string{
+	ERROR_LOCATION_ID28_21:         "ID28..21",
+	ERROR_LOCATION_SOF:             "SOF",
+	ERROR_LOCATION_SRTR:            "SRTR",
+	ERROR_LOCATION_IDE:             "IDE",
+	ERROR_LOCATION_ID20_18:         "ID20..18",
+	ERROR_LOCATION_ID17_13:         "ID17..13",
+	ERROR_LOCATION_CRC:             "CRC",
+	ERROR_LOCATION_R0:              "R0",
+	ERROR_LOCATION_DATA:            "Data",
+	ERROR_LOCATION_DLC:             "DLC",
+	ERROR_LOCATION_RTR:             "RTR",
+	ERROR_LOCATION_R1:              "R1",
+	ERROR_LOCATION_ID4_0:           "ID4..0",
+	ERROR_LOCATION_ID12_5:          "ID12..5",
+	ERROR_LOCATION_ACTIVE_FLAG:     "Active error flag",
+	ERROR_LOCATION_INTERMISSION:    "Intermission",
+	ERROR_LOCATION_DOMINANT_BITS:   "Tolerate dominant bits",
+	ERROR_LOCATION_PASSIVE_FLAG:    "Passive error flag",
+	ERROR_LOCATION_ERROR_DELIMITER: "Error delimiter",
+	ERROR_LOCATION_CRC_DELIMITER:   "CRC delimiter",
+	ERROR_LOCATION_ACK_SLOT:        "ACK slot",
+	ERROR_LOCATION_EOF:             "EOF",
+	ERROR_LOCATION_ACK_DELIMITER:   "ACK delimiter",
+	ERROR_LOCATION_OVERLOAD_FLAG:   "Overload flag",
+}
+
+// Returns the name of the frame segment, e.g. "ACK slot"
+func (l ErrorLocation) String() string {
+	if name, found := errorLocationNames[l]; found {
+		return name
+	}
+	return fmt.Sprintf("Unknown (0x%02X)", uint8(l))
+}
+
+// Returns the name of the error type, e.g. "Stuff"
+func (t ErrorFrameType) String() string {
+	switch t {
+	case ERROR_FRAME_BIT:
+		return "Bit"
+	case ERROR_FRAME_FORM:
+		return "Form"
+	case ERROR_FRAME_STUFF:
+		return "Stuff"
+	case ERROR_FRAME_OTHER:
+		return "Other"
+	}
+	return fmt.Sprintf("Unknown (0x%X)", uint32(t))
+}
+
+// Decodes the payload of an error frame, returns false if the message is no error frame or its payload is too short
+// Note: The error type is stored in the identifier. The data bytes are laid out as follows:
+//   - Data[0]: Direction, 0 if the error was detected while transmitting and 1 while receiving
+//   - Data[1]: Error code capture (ECC) of the controller, bits 4..0 hold the ErrorLocation
+//   - Data[2]: Receive error counter (REC)
+//   - Data[3]: Transmit error counter (TEC)
+func (m *TPCANMsg) ErrorFrameInfo() (ErrorFrameInfo, bool) {
+	if m.MsgType&PCAN_MESSAGE_ERRFRAME == 0 || m.DLC < 4 {
+		return ErrorFrameInfo{}, false
+	}
+	direction := DirectionTx
+	if m.Data[0] != 0 {
+		direction = DirectionRx
+	}
+	return ErrorFrameInfo{
+		Type:      ErrorFrameType(m.ID),
+		Direction: direction,
+		ECC:       m.Data[1],
+		Location:  ErrorLocation(m.Data[1] & 0x1F),
+		REC:       m.Data[2],
+		TEC:       m.Data[3],
+	}, true
+}
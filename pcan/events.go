@@ -0,0 +1,102 @@
+package pcan
+
+import (
+	"encoding/binary"
+)
+
+/* Typed events for received data, status and error frames. */
+
+// A received message as typed event, one of DataFrame, StatusFrame or ErrorFrame
+type BusEvent interface {
+	Message() *TPCANMsg        // Received message the event was decoded from
+	Received() *TPCANTimestamp // Timestamp of the received message
+}
+
+// A received data frame, including echo and RTR frames
+type DataFrame struct {
+	Kind      FrameKind // FrameKindData, FrameKindEcho or FrameKindRTR
+	Direction Direction // DirectionTx for echoes of own transmissions, DirectionRx otherwise
+	Msg       TPCANMsg
+	Timestamp TPCANTimestamp
+}
+
+// Direction of a data frame seen on the bus relative to this channel
+type Direction int
+
+const (
+	DirectionRx Direction = iota // Frame sent by another node
+	DirectionTx                  // Frame sent by this channel and read back as echo (PCAN_MESSAGE_ECHO)
+)
+
+// Returns the short name of the direction
+func (d Direction) String() string {
+	if d == DirectionTx {
+		return "Tx"
+	}
+	return "Rx"
+}
+
+// Returns the direction of a frame with the given message type, echo frames are own transmissions
+func DirectionOf(msgType TPCANMessageType) Direction {
+	if msgType&PCAN_MESSAGE_ECHO != 0 {
+		return DirectionTx
+	}
+	return DirectionRx
+}
+
+// A received PCAN status message
+type StatusFrame struct {
+	Status    TPCANStatus // Status reported by the driver
+	Msg       TPCANMsg
+	Timestamp TPCANTimestamp
+}
+
+// A received error frame
+type ErrorFrame struct {
+	Type      ErrorFrameType // Kind of the detected bus error
+	Info      ErrorFrameInfo // Decoded payload, only valid if InfoValid is set
+	InfoValid bool           // Set if the payload could be decoded, see TPCANMsg.ErrorFrameInfo()
+	Msg       TPCANMsg
+	Timestamp TPCANTimestamp
+}
+
+// Kind of a bus error reported by an error frame, encoded in the message identifier
+type ErrorFrameType uint32
+
+const (
+	ERROR_FRAME_BIT   = ErrorFrameType(0x01) // Bit error
+	ERROR_FRAME_FORM  = ErrorFrameType(0x02) // Form error
+	ERROR_FRAME_STUFF = ErrorFrameType(0x04) // Stuff error
+	ERROR_FRAME_OTHER = ErrorFrameType(0x08) // Other error (e.g. CRC or acknowledge error)
+)
+
+func (e *DataFrame) Message() *TPCANMsg          { return &e.Msg }
+func (e *DataFrame) Received() *TPCANTimestamp   { return &e.Timestamp }
+func (e *StatusFrame) Message() *TPCANMsg        { return &e.Msg }
+func (e *StatusFrame) Received() *TPCANTimestamp { return &e.Timestamp }
+func (e *ErrorFrame) Message() *TPCANMsg         { return &e.Msg }
+func (e *ErrorFrame) Received() *TPCANTimestamp  { return &e.Timestamp }
+
+// Reads a CAN message from the receive queue and returns it as typed event
+// Note: Does return nil if receive buffer is empty
+func (p *TPCANBus) ReadEvent() (TPCANStatus, BusEvent, error) {
+	status, msg, timestamp, err := p.Read()
+	if msg == nil || timestamp == nil {
+		return status, nil, err
+	}
+	return status, NewBusEvent(msg, timestamp), err
+}
+
+// Decodes a received message into a typed event
+// Note: The status of a status message is stored big endian in the first four data bytes
+func NewBusEvent(msg *TPCANMsg, timestamp *TPCANTimestamp) BusEvent {
+	switch kind := ClassifyMsgType(msg.MsgType); kind {
+	case FrameKindStatus:
+		return &StatusFrame{Status: TPCANStatus(binary.BigEndian.Uint32(msg.Data[0:4])), Msg: *msg, Timestamp: *timestamp}
+	case FrameKindError:
+		info, valid := msg.ErrorFrameInfo()
+		return &ErrorFrame{Type: ErrorFrameType(msg.ID), Info: info, InfoValid: valid, Msg: *msg, Timestamp: *timestamp}
+	default:
+		return &DataFrame{Kind: kind, Direction: DirectionOf(msg.MsgType), Msg: *msg, Timestamp: *timestamp}
+	}
+}
@@ -0,0 +1,52 @@
+package pcan
+
+import (
+	"testing"
+)
+
+/* Tests of the typed events of received messages. */
+
+func TestReadEventDirection(t *testing.T) {
+	frame := func(id TPCANMsgID, msgType TPCANMessageType) stubFrame {
+		return stubFrame{status: PCAN_ERROR_OK, msg: TPCANMsg{ID: id, MsgType: msgType, DLC: 1}}
+	}
+	tests := []struct {
+		frame     stubFrame
+		kind      FrameKind
+		direction Direction
+	}{
+		{frame(0x100, PCAN_MESSAGE_STANDARD), FrameKindData, DirectionRx},
+		{frame(0x101, PCAN_MESSAGE_STANDARD|PCAN_MESSAGE_ECHO), FrameKindEcho, DirectionTx},
+		{frame(0x102, PCAN_MESSAGE_EXTENDED), FrameKindData, DirectionRx},
+		{frame(0x103, PCAN_MESSAGE_EXTENDED|PCAN_MESSAGE_ECHO), FrameKindEcho, DirectionTx},
+		{frame(0x104, PCAN_MESSAGE_RTR), FrameKindRTR, DirectionRx},
+		{frame(0x105, PCAN_MESSAGE_RTR|PCAN_MESSAGE_ECHO), FrameKindEcho, DirectionTx},
+		{frame(0x106, PCAN_MESSAGE_STANDARD), FrameKindData, DirectionRx},
+	}
+	frames := make([]stubFrame, 0, len(tests)+1)
+	for _, test := range tests {
+		frames = append(frames, test.frame)
+	}
+	frames = append(frames, frame(0, PCAN_MESSAGE_STATUS))
+	stubRead(t, frames...)
+
+	bus := &TPCANBus{Handle: PCAN_USBBUS1}
+	for _, test := range tests {
+		_, event, err := bus.ReadEvent()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, ok := event.(*DataFrame)
+		if !ok {
+			t.Fatalf("event of message 0x%X is %T, want *DataFrame", test.frame.msg.ID, event)
+		}
+		if data.Msg.ID != test.frame.msg.ID || data.Kind != test.kind || data.Direction != test.direction {
+			t.Errorf("event of message 0x%X = %v %v, want %v %v", data.Msg.ID, data.Kind, data.Direction, test.kind, test.direction)
+		}
+	}
+	if _, event, _ := bus.ReadEvent(); event == nil {
+		t.Error("status frame is missing")
+	} else if _, ok := event.(*StatusFrame); !ok {
+		t.Errorf("event of status message is %T, want *StatusFrame", event)
+	}
+}
@@ -0,0 +1,232 @@
+package pcan
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+/* Bit timing and bit rate adapting of FD capable channels. */
+
+// Parses the parameters of a FD bit rate string
+// bitRateFD: Bit rate string, e.g. f_clock=80000000,nom_brp=10,nom_tseg1=5,nom_tseg2=2,nom_sjw=1,data_brp=4,data_tseg1=7,data_tseg2=2,data_sjw=1
+func ParseBitrateFD(bitRateFD TPCANBitrateFD) (map[TPCANBRParameter]uint32, error) {
+	params := map[TPCANBRParameter]uint32{}
+	for _, pair := range strings.Split(string(bitRateFD), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("bit rate parameter %q is no key=value pair", pair)
+		}
+		val, err := strconv.ParseUint(strings.TrimSpace(value), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of bit rate parameter %q: %v", key, err)
+		}
+		params[TPCANBRParameter(strings.TrimSpace(key))] = uint32(val)
+	}
+	return params, nil
+}
+
+// Returns the sample points of the nominal and data phase in percent computed from a FD bit rate string
+// Note: The sample point is the share of the bit time up to the end of TSEG1: (1 + tseg1) / (1 + tseg1 + tseg2)
+func SamplePointsFD(bitRateFD TPCANBitrateFD) (float64, float64, error) {
+	params, err := ParseBitrateFD(bitRateFD)
+	if err != nil {
+		return 0, 0, err
+	}
+	nominal, err := samplePoint(params, PCAN_BR_NOM_TSEG1, PCAN_BR_NOM_TSEG2)
+	if err != nil {
+		return 0, 0, err
+	}
+	data, err := samplePoint(params, PCAN_BR_DATA_TSEG1, PCAN_BR_DATA_TSEG2)
+	if err != nil {
+		return 0, 0, err
+	}
+	return nominal, data, nil
+}
+
+// Enables or disables bit rate adapting of a channel, so it can be initialized while another application uses it with its bit rate
+// handle: The handle of a PCAN Channel
+// on: Bit rate adapting is used if set to true
+// Note: The parameter is only settable before the channel is initialized, use GetBitrateAdapting() on an initialized channel
+func SetBitrateAdapting(handle TPCANHandle, on bool) (TPCANStatus, error) {
+	if err := loadAPIImplicitly(); err != nil {
+		return PCAN_ERROR_NODRIVER, err
+	}
+	var conv = map[bool]TPCANParameterValue{false: PCAN_PARAMETER_OFF, true: PCAN_PARAMETER_ON}
+	val := conv[on]
+	return APISetValue(handle, PCAN_BITRATE_ADAPTING, unsafe.Pointer(&val), uint32(unsafe.Sizeof(val)))
+}
+
+// Returns if the channel was initialized using bit rate adapting (read-only after initialization)
+func (p *TPCANBusFD) GetBitrateAdapting() (TPCANStatus, bool, error) {
+	var val TPCANParameterValue
+	status, err := APIGetValue(p.Handle, PCAN_BITRATE_ADAPTING, unsafe.Pointer(&val), uint32(unsafe.Sizeof(val)))
+	if status != PCAN_ERROR_OK || err != nil {
+		return status, false, err
+	}
+	return status, val == PCAN_PARAMETER_ON, err
+}
+
+// Returns the bit rate string the channel is operating with as reported by the driver (read-only)
+func (p *TPCANBusFD) GetBitrateInfoFD() (TPCANStatus, TPCANBitrateFD, error) {
+	var buffer [MAX_LENGHT_STRING_BUFFER]byte
+	status, err := APIGetValue(p.Handle, PCAN_BITRATE_INFO_FD, unsafe.Pointer(&buffer), uint32(unsafe.Sizeof(buffer)))
+	if status != PCAN_ERROR_OK || err != nil {
+		return status, "", err
+	}
+	return status, TPCANBitrateFD(cString(buffer[:])), err
+}
+
+// Returns the effective sample points of the nominal and data phase in percent (read-only)
+// Note: The bit rate is read from the driver, as with bit rate adapting it can differ from the one the bus was created with
+func (p *TPCANBusFD) SamplePoints() (TPCANStatus, float64, float64, error) {
+	status, bitRateFD, err := p.GetBitrateInfoFD()
+	if status != PCAN_ERROR_OK || err != nil {
+		return status, 0, 0, err
+	}
+	nominal, data, err := SamplePointsFD(bitRateFD)
+	return status, nominal, data, err
+}
+
+// Returns the delay in microseconds inserted between two sent frames
+func (p *TPCANBusFD) GetInterframeDelay() (TPCANStatus, uint32, error) {
+	var val uint32
+	status, err := APIGetValue(p.Handle, PCAN_INTERFRAME_DELAY, unsafe.Pointer(&val), uint32(unsafe.Sizeof(val)))
+	return status, val, err
+}
+
+// Sets the delay in microseconds inserted between two sent frames (settable on initialized channels)
+// Note: Only devices with FEATURE_DELAY_CAPABLE support a delay, others return PCAN_ERROR_ILLPARAMTYPE
+func (p *TPCANBusFD) SetInterframeDelay(micros uint32) (TPCANStatus, error) {
+	return APISetValue(p.Handle, PCAN_INTERFRAME_DELAY, unsafe.Pointer(&micros), uint32(unsafe.Sizeof(micros)))
+}
+
+// Frame format of CAN FD frames
+type FDMode int
+
+const (
+	FDModeISO    FDMode = iota // CAN FD frames as standardized in ISO 11898-1:2015 including the stuff bit counter in the CRC
+	FDModeNonISO               // CAN FD frames of the original Bosch specification used by legacy devices
+)
+
+// Returns the name of the frame format
+func (m FDMode) String() string {
+	if m == FDModeNonISO {
+		return "non-ISO"
+	}
+	return "ISO"
+}
+
+// Returns the CAN FD frame format the channel is operating with
+// Note: The PCAN-Basic driver does not expose the frame format, so ErrNotSupported is always returned.
+// The format is a setting of the device (e.g. in its driver configuration) and must be consistent with all other
+// nodes of the network, otherwise every FD frame is rejected with CRC errors.
+func (p *TPCANBusFD) GetFDMode() (TPCANStatus, FDMode, error) {
+	return PCAN_ERROR_ILLPARAMTYPE, FDModeISO, ErrNotSupported
+}
+
+// Selects the CAN FD frame format of the channel
+// mode: Frame format, must be consistent with all other nodes of the network
+// Note: The PCAN-Basic driver has no parameter to select the frame format, so ErrNotSupported is always returned.
+// Configure the format in the settings of the device before initializing the channel.
+func (p *TPCANBusFD) SetFDMode(mode FDMode) (TPCANStatus, error) {
+	return PCAN_ERROR_ILLPARAMTYPE, fmt.Errorf("selecting %v CAN FD mode: %w", mode, ErrNotSupported)
+}
+
+// computes the sample point in percent from the time segments of a phase
+func samplePoint(params map[TPCANBRParameter]uint32, tseg1Key TPCANBRParameter, tseg2Key TPCANBRParameter) (float64, error) {
+	tseg1, found1 := params[tseg1Key]
+	tseg2, found2 := params[tseg2Key]
+	if !found1 || !found2 {
+		return 0, fmt.Errorf("bit rate string is missing %v or %v", tseg1Key, tseg2Key)
+	}
+	return 100 * float64(1+tseg1) / float64(1+tseg1+tseg2), nil
+}
+
+// Clock frequencies in MHz, nominal and data bit rates in bit/s supported by BuildBitrateFD()
+var (
+	SupportedClocksFD       = []uint32{20, 24, 30, 40, 60, 80}
+	SupportedNominalRatesFD = []uint32{125000, 250000, 500000, 1000000}
+	SupportedDataRatesFD    = []uint32{1000000, 2000000, 4000000, 5000000, 8000000}
+)
+
+// limits of the bit timing registers of a single phase
+type phaseLimits struct {
+	maxBrp, maxTseg1, maxTseg2 uint32
+}
+
+var (
+	nominalPhaseLimits = phaseLimits{maxBrp: 1024, maxTseg1: 256, maxTseg2: 128}
+	dataPhaseLimits    = phaseLimits{maxBrp: 1024, maxTseg1: 32, maxTseg2: 16}
+)
+
+const minTimeQuantaFD = 8 // fewer time quanta per bit leave no room to place the sample point
+
+// bit timing of a single phase
+type phaseTiming struct {
+	brp, tseg1, tseg2, sjw uint32
+}
+
+// Builds a FD bit rate string from plain bit rates with a sample point of about 80% in both phases
+// clockMHz: Clock frequency of the CAN controller in MHz, one of SupportedClocksFD
+// nominalBitrate: Bit rate of the arbitration phase in bit/s, one of SupportedNominalRatesFD
+// dataBitrate: Bit rate of the data phase in bit/s, one of SupportedDataRatesFD
+// Note: A combination is supported if the clock is divisible into at least 8 time quanta per bit of both phases.
+// All nominal bit rates and data bit rates of 1 and 2 Mbit/s work with every clock, 4 and 5 Mbit/s need a clock
+// of 40 MHz or more and 8 Mbit/s needs 80 MHz.
+// The error of an unsupported combination lists the valid bit rates for the clock.
+func BuildBitrateFD(clockMHz uint32, nominalBitrate uint32, dataBitrate uint32) (TPCANBitrateFD, error) {
+	if !slices.Contains(SupportedClocksFD, clockMHz) {
+		return "", fmt.Errorf("clock of %v MHz is not supported, valid clocks in MHz are %v", clockMHz, SupportedClocksFD)
+	}
+	clock := clockMHz * 1000000
+
+	nominal, okNominal := phaseTimingFor(clock, nominalBitrate, nominalPhaseLimits)
+	data, okData := phaseTimingFor(clock, dataBitrate, dataPhaseLimits)
+	if !okNominal || !okData || !slices.Contains(SupportedNominalRatesFD, nominalBitrate) || !slices.Contains(SupportedDataRatesFD, dataBitrate) {
+		return "", fmt.Errorf("bit rates of %v bit/s nominal and %v bit/s data are not supported with a %v MHz clock, valid are nominal %v and data %v",
+			nominalBitrate, dataBitrate, clockMHz, validRatesFD(clock, SupportedNominalRatesFD, nominalPhaseLimits), validRatesFD(clock, SupportedDataRatesFD, dataPhaseLimits))
+	}
+
+	return TPCANBitrateFD(fmt.Sprintf("%v=%v,%v=%v,%v=%v,%v=%v,%v=%v,%v=%v,%v=%v,%v=%v,%v=%v", PCAN_BR_CLOCK, clock,
+		PCAN_BR_NOM_BRP, nominal.brp, PCAN_BR_NOM_TSEG1, nominal.tseg1, PCAN_BR_NOM_TSEG2, nominal.tseg2, PCAN_BR_NOM_SJW, nominal.sjw,
+		PCAN_BR_DATA_BRP, data.brp, PCAN_BR_DATA_TSEG1, data.tseg1, PCAN_BR_DATA_TSEG2, data.tseg2, PCAN_BR_DATA_SJW, data.sjw)), nil
+}
+
+// finds the timing with the most time quanta per bit fitting into the register limits
+func phaseTimingFor(clock uint32, bitrate uint32, limits phaseLimits) (phaseTiming, bool) {
+	if bitrate == 0 {
+		return phaseTiming{}, false
+	}
+	for brp := uint32(1); brp <= limits.maxBrp; brp++ {
+		if clock%(brp*bitrate) != 0 {
+			continue
+		}
+		quanta := clock / (brp * bitrate)
+		if quanta < minTimeQuantaFD {
+			break
+		}
+		tseg2 := (quanta + 2) / 5 // 20% of the bit time after the sample point
+		tseg1 := quanta - 1 - tseg2
+		if tseg1 <= limits.maxTseg1 && tseg2 <= limits.maxTseg2 {
+			return phaseTiming{brp: brp, tseg1: tseg1, tseg2: tseg2, sjw: tseg2}, true
+		}
+	}
+	return phaseTiming{}, false
+}
+
+// returns the bit rates for which a timing exists with the given clock
+func validRatesFD(clock uint32, rates []uint32, limits phaseLimits) []uint32 {
+	var valid []uint32
+	for _, rate := range rates {
+		if _, ok := phaseTimingFor(clock, rate, limits); ok {
+			valid = append(valid, rate)
+		}
+	}
+	return valid
+}
@@ -0,0 +1,84 @@
+package pcan
+
+/* Composable software filters for received CAN messages. */
+
+// Decides if a received message is passed on
+type Filter interface {
+	Allow(msg *TPCANMsg) bool
+}
+
+// Adapter to use a function as Filter
+type FilterFunc func(msg *TPCANMsg) bool
+
+// Calls the function itself
+func (f FilterFunc) Allow(msg *TPCANMsg) bool {
+	return f(msg)
+}
+
+// Allows messages with one of the given identifiers
+func ByID(ids ...TPCANMsgID) Filter {
+	return FilterFunc(func(msg *TPCANMsg) bool {
+		for _, id := range ids {
+			if msg.ID == id {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Allows messages with an identifier between fromID and toID (both included)
+func ByIDRange(fromID TPCANMsgID, toID TPCANMsgID) Filter {
+	return FilterFunc(func(msg *TPCANMsg) bool {
+		return msg.ID >= fromID && msg.ID <= toID
+	})
+}
+
+// Allows messages whose identifier matches the code in all bits set in the mask
+func ByMask(code TPCANMsgID, mask TPCANMsgID) Filter {
+	return FilterFunc(func(msg *TPCANMsg) bool {
+		return msg.ID&mask == code&mask
+	})
+}
+
+// Allows messages with all bits of the given message type set
+// Note: PCAN_MESSAGE_STANDARD allows all messages without the extended flag
+func ByType(msgType TPCANMessageType) Filter {
+	return FilterFunc(func(msg *TPCANMsg) bool {
+		if msgType == PCAN_MESSAGE_STANDARD {
+			return msg.MsgType&PCAN_MESSAGE_EXTENDED == 0
+		}
+		return msg.MsgType&msgType == msgType
+	})
+}
+
+// Allows messages passing all given filters
+func And(filters ...Filter) Filter {
+	return FilterFunc(func(msg *TPCANMsg) bool {
+		for _, f := range filters {
+			if !f.Allow(msg) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Allows messages passing at least one of the given filters
+func Or(filters ...Filter) Filter {
+	return FilterFunc(func(msg *TPCANMsg) bool {
+		for _, f := range filters {
+			if f.Allow(msg) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Allows messages not passing the given filter
+func Not(filter Filter) Filter {
+	return FilterFunc(func(msg *TPCANMsg) bool {
+		return !filter.Allow(msg)
+	})
+}
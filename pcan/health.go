@@ -0,0 +1,109 @@
+package pcan
+
+import (
+	"context"
+	"time"
+)
+
+/* Classification and watching of the error state of the CAN controller. */
+
+// Error state of the CAN controller derived from the bus error bits of the channel status
+// Note: The error counters (REC, TEC) the state is based on are not available, the PCAN-Basic driver only reports
+// the reached error level, see ErrorCounters(). Error frames carry the counters, see TPCANMsg.ErrorFrameInfo().
+type BusState int
+
+const (
+	BusStateOK      BusState = iota // No bus error reported
+	BusStateLight                   // An error counter reached the 'light' limit (PCAN_ERROR_BUSLIGHT)
+	BusStateWarning                 // An error counter reached the 'warning' limit (PCAN_ERROR_BUSWARNING)
+	BusStatePassive                 // The controller is error passive (PCAN_ERROR_BUSPASSIVE)
+	BusStateOff                     // The controller is in bus-off state (PCAN_ERROR_BUSOFF)
+)
+
+// Returns the name of the bus state
+func (s BusState) String() string {
+	switch s {
+	case BusStateOK:
+		return "OK"
+	case BusStateLight:
+		return "Light"
+	case BusStateWarning:
+		return "Warning"
+	case BusStatePassive:
+		return "Passive"
+	case BusStateOff:
+		return "BusOff"
+	default:
+		return "Unknown"
+	}
+}
+
+// Returns the bus state of a channel status, the most severe error bit wins
+func BusStateOf(status TPCANStatus) BusState {
+	switch {
+	case status&PCAN_ERROR_BUSOFF != 0:
+		return BusStateOff
+	case status&PCAN_ERROR_BUSPASSIVE != 0:
+		return BusStatePassive
+	case status&PCAN_ERROR_BUSWARNING != 0:
+		return BusStateWarning
+	case status&PCAN_ERROR_BUSLIGHT != 0:
+		return BusStateLight
+	default:
+		return BusStateOK
+	}
+}
+
+// Returns the current error state of the CAN controller
+// Note: The returned status is the raw channel status of GetStatus()
+func (p *TPCANBus) GetBusState() (TPCANStatus, BusState, error) {
+	status, err := p.GetStatus()
+	return status, BusStateOf(status), err
+}
+
+// Event emitted by Watchdog when the bus state changed
+type BusStateEvent struct {
+	Previous BusState    // State before the change
+	State    BusState    // State after the change
+	Status   TPCANStatus // Channel status the state was derived from
+	Time     time.Time   // Time the change was detected
+}
+
+// Periodically checks the bus state and emits an event whenever it changed
+// interval: Time between two checks
+// Note: The bus is assumed to be OK when the watchdog starts, so an initially faulty bus is reported by the first check.
+// Failed status reads are skipped. The returned channel is closed after ctx is cancelled.
+func (p *TPCANBus) Watchdog(ctx context.Context, interval time.Duration) <-chan BusStateEvent {
+	events := make(chan BusStateEvent, 8)
+
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		previous := BusStateOK
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			status, state, err := p.GetBusState()
+			if err != nil || state == previous {
+				continue
+			}
+
+			event := BusStateEvent{Previous: previous, State: state, Status: status, Time: time.Now()}
+			previous = state
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events
+}
@@ -0,0 +1,78 @@
+package pcan
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+/* Helpers for PCAN-LAN channels connected through a PCAN-Gateway and the Virtual PCAN-Gateway service. */
+
+// device type used to look up PCAN-LAN channels
+const lanDeviceType = "PCAN_LAN"
+
+// Returns if the handle belongs to a PCAN-LAN channel
+func IsLANHandle(handle TPCANHandle) bool {
+	return handle >= PCAN_LANBUS1 && handle <= PCAN_LANBUS16
+}
+
+// Finds the PCAN-LAN channel connected to the gateway with given IP address
+// ip: IPv4 address of the PCAN-Gateway
+func LookUpLANChannel(ip string) (TPCANStatus, TPCANHandle, error) {
+	return LookUpChannelWithOptions(LookUpOptions{DeviceType: lanDeviceType, IPAddress: ip})
+}
+
+// Initializes a PCAN-LAN channel of a PCAN-Gateway
+// handle: The handle of a PCAN-LAN channel, PCAN_NONEBUS looks up the channel by the IP address
+// ip: IPv4 address of the PCAN-Gateway, if not empty it must match the address of the channel
+// baudRate: The speed for the communication (BTR0BTR1 code)
+// Note: Routes and ports of the gateway are configured in the gateway and the Virtual PCAN-Gateway service, the driver does not expose them
+func InitializeLAN(handle TPCANHandle, ip string, baudRate TPCANBaudrate) (TPCANStatus, *TPCANBus, error) {
+	if err := loadAPIImplicitly(); err != nil {
+		return PCAN_ERROR_NODRIVER, nil, err
+	}
+
+	if handle != PCAN_NONEBUS && !IsLANHandle(handle) {
+		return PCAN_ERROR_ILLHW, nil, fmt.Errorf("handle 0x%X is no PCAN-LAN channel", handle)
+	}
+	if handle == PCAN_NONEBUS && ip == "" {
+		return PCAN_ERROR_ILLPARAMVAL, nil, fmt.Errorf("either a PCAN-LAN handle or an IP address is needed")
+	}
+
+	if ip != "" {
+		status, found, err := LookUpLANChannel(ip)
+		if status != PCAN_ERROR_OK || err != nil {
+			return status, nil, err
+		}
+		if found == PCAN_NONEBUS {
+			return status, nil, fmt.Errorf("%w: no PCAN-LAN channel with IP address %v", ErrNoChannel, ip)
+		}
+		if handle != PCAN_NONEBUS && handle != found {
+			return PCAN_ERROR_ILLHW, nil, fmt.Errorf("PCAN-LAN channel with IP address %v has handle 0x%X instead of 0x%X", ip, found, handle)
+		}
+		handle = found
+	}
+
+	return InitializeBasic(handle, baudRate)
+}
+
+// Returns the status of the Virtual PCAN-Gateway service
+func LANServiceStatus() (TPCANStatus, TPCANStatusValue, error) {
+	if err := loadAPIImplicitly(); err != nil {
+		return PCAN_ERROR_NODRIVER, 0, err
+	}
+
+	var serviceStatus TPCANStatusValue
+	status, err := APIGetValue(PCAN_NONEBUS, PCAN_LAN_SERVICE_STATUS, unsafe.Pointer(&serviceStatus), uint32(unsafe.Sizeof(serviceStatus)))
+	return status, serviceStatus, err
+}
+
+// Returns the remote IPv4 address of a PCAN-LAN channel
+func (p *TPCANBus) GetIPAddress() (TPCANStatus, string, error) {
+	return p.getStringValue(PCAN_IP_ADDRESS)
+}
+
+// Returns the communication direction of a PCAN-LAN channel
+func (p *TPCANBus) GetLANDirection() (TPCANStatus, TPCANLANDirection, error) {
+	status, val, err := p.GetParameter(PCAN_LAN_CHANNEL_DIRECTION)
+	return status, TPCANLANDirection(val), err
+}
@@ -0,0 +1,363 @@
+package pcan
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+/* Helper functions to access the payload of CAN messages. */
+
+// Returns if the identifier is above the 11-bit range and therefore needs an extended frame
+func (id TPCANMsgID) IsExtendedRange() bool {
+	return id > MAX_STANDARD_ID
+}
+
+// Returns the identifier as 11-bit value and whether it fits into the standard range (0..MAX_STANDARD_ID)
+func (id TPCANMsgID) Standard() (uint16, bool) {
+	return uint16(id & MAX_STANDARD_ID), id <= MAX_STANDARD_ID
+}
+
+// Returns the identifier as 29-bit value and whether it fits into the extended range (0..MAX_EXTENDED_ID)
+func (id TPCANMsgID) Extended() (uint32, bool) {
+	return uint32(id & MAX_EXTENDED_ID), id <= MAX_EXTENDED_ID
+}
+
+// masks the identifier of a received data or RTR frame to 11 bits for standard and 29 bits for extended frames
+// Note: Status and error frames are not changed, their identifier carries no CAN identifier
+func normalizeID(id TPCANMsgID, msgType TPCANMessageType) TPCANMsgID {
+	switch {
+	case msgType&(PCAN_MESSAGE_STATUS|PCAN_MESSAGE_ERRFRAME) != 0:
+		return id
+	case msgType&PCAN_MESSAGE_EXTENDED != 0:
+		return id & MAX_EXTENDED_ID
+	default:
+		return id & MAX_STANDARD_ID
+	}
+}
+
+// Creates a classic CAN message with a 11-bit identifier, the data bytes not covered by the DLC are zero
+// id: Standard identifier (0..0x7FF)
+// data: Payload of up to 8 bytes, the DLC is set to its length
+func NewStandardMsg(id TPCANMsgID, data []byte) (TPCANMsg, error) {
+	return newMsg(id, false, data)
+}
+
+// Creates a classic CAN message with a 29-bit identifier, the data bytes not covered by the DLC are zero
+// id: Extended identifier (0..0x1FFFFFFF)
+// data: Payload of up to 8 bytes, the DLC is set to its length
+func NewExtendedMsg(id TPCANMsgID, data []byte) (TPCANMsg, error) {
+	return newMsg(id, true, data)
+}
+
+// Zeroes all data bytes not covered by the DLC, so a reused message does not carry stale payload
+func (m *TPCANMsg) Truncate() {
+	clear(m.Data[min(m.DLC, LENGTH_DATA_CAN_MESSAGE):])
+}
+
+// Returns the message as readable text showing only the valid data bytes (DLC), e.g. "0x123 [3] 01 02 03"
+func (m TPCANMsg) String() string {
+	var sb strings.Builder
+	if m.MsgType&PCAN_MESSAGE_EXTENDED != 0 {
+		fmt.Fprintf(&sb, "0x%08X", m.ID)
+	} else {
+		fmt.Fprintf(&sb, "0x%03X", m.ID)
+	}
+	fmt.Fprintf(&sb, " [%v]", m.DLC)
+	if m.MsgType&PCAN_MESSAGE_RTR != 0 {
+		sb.WriteString(" RTR")
+		return sb.String()
+	}
+	for _, b := range m.Data[:min(m.DLC, LENGTH_DATA_CAN_MESSAGE)] {
+		fmt.Fprintf(&sb, " %02X", b)
+	}
+	return sb.String()
+}
+
+// Returns the uint16 stored at the given byte offset of the message data
+// offset: Byte offset within the valid data (DLC)
+// be: Value is stored big endian if set to true, little endian otherwise
+func (m *TPCANMsg) Uint16(offset int, be bool) (uint16, error) {
+	data, err := m.payload(offset, 2)
+	if err != nil {
+		return 0, err
+	}
+	return byteOrder(be).Uint16(data), nil
+}
+
+// Returns the uint32 stored at the given byte offset of the message data
+// offset: Byte offset within the valid data (DLC)
+// be: Value is stored big endian if set to true, little endian otherwise
+func (m *TPCANMsg) Uint32(offset int, be bool) (uint32, error) {
+	data, err := m.payload(offset, 4)
+	if err != nil {
+		return 0, err
+	}
+	return byteOrder(be).Uint32(data), nil
+}
+
+// Stores an uint16 at the given byte offset of the message data and increases the DLC if needed
+// offset: Byte offset within the message data
+// be: Value is stored big endian if set to true, little endian otherwise
+func (m *TPCANMsg) SetUint16(offset int, be bool, value uint16) error {
+	if err := m.grow(offset, 2); err != nil {
+		return err
+	}
+	byteOrder(be).PutUint16(m.Data[offset:], value)
+	return nil
+}
+
+// Stores an uint32 at the given byte offset of the message data and increases the DLC if needed
+// offset: Byte offset within the message data
+// be: Value is stored big endian if set to true, little endian otherwise
+func (m *TPCANMsg) SetUint32(offset int, be bool, value uint32) error {
+	if err := m.grow(offset, 4); err != nil {
+		return err
+	}
+	byteOrder(be).PutUint32(m.Data[offset:], value)
+	return nil
+}
+
+// Encodes the message in a compact binary layout: 4 byte little endian ID, 1 byte message type, 1 byte DLC and DLC data bytes
+// Note: RTR frames carry no data bytes, so the encoded size is between 6 and 14 bytes
+func (m *TPCANMsg) MarshalBinary() ([]byte, error) {
+	if m.DLC > LENGTH_DATA_CAN_MESSAGE {
+		return nil, fmt.Errorf("DLC of %v exceeds maximum of %v", m.DLC, LENGTH_DATA_CAN_MESSAGE)
+	}
+	dataLen := m.dataLen()
+	buffer := make([]byte, binaryMsgHeaderSize, binaryMsgHeaderSize+dataLen)
+	binary.LittleEndian.PutUint32(buffer, uint32(m.ID))
+	buffer[4] = byte(m.MsgType)
+	buffer[5] = m.DLC
+	return append(buffer, m.Data[:dataLen]...), nil
+}
+
+// Decodes a message encoded by MarshalBinary, data bytes not covered by the DLC are zero
+func (m *TPCANMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < binaryMsgHeaderSize {
+		return fmt.Errorf("encoded message of %v bytes is shorter than its header of %v bytes", len(data), binaryMsgHeaderSize)
+	}
+	msg := TPCANMsg{
+		ID:      TPCANMsgID(binary.LittleEndian.Uint32(data)),
+		MsgType: TPCANMessageType(data[4]),
+		DLC:     data[5]}
+	if msg.DLC > LENGTH_DATA_CAN_MESSAGE {
+		return fmt.Errorf("DLC of %v exceeds maximum of %v", msg.DLC, LENGTH_DATA_CAN_MESSAGE)
+	}
+	if len(data) != binaryMsgHeaderSize+msg.dataLen() {
+		return fmt.Errorf("encoded message of %v bytes does not match DLC of %v", len(data), msg.DLC)
+	}
+	copy(msg.Data[:], data[binaryMsgHeaderSize:])
+	*m = msg
+	return nil
+}
+
+// size of the header of a binary encoded message: ID, message type and DLC
+const binaryMsgHeaderSize = 6
+
+// returns the amount of data bytes transported with the message
+func (m *TPCANMsg) dataLen() int {
+	if m.MsgType&PCAN_MESSAGE_RTR != 0 {
+		return 0
+	}
+	return int(min(m.DLC, LENGTH_DATA_CAN_MESSAGE))
+}
+
+// Returns if two messages are equal, only the valid data bytes (DLC) are compared
+func Equal(a, b *TPCANMsg) bool {
+	return a.ID == b.ID && a.MsgType == b.MsgType && a.DLC == b.DLC && bytes.Equal(a.Data[:a.dataLen()], b.Data[:b.dataLen()])
+}
+
+// Returns if two CAN FD messages are equal, only the valid data bytes (length of the DLC code) are compared
+func EqualFD(a, b *TPCANMsgFD) bool {
+	length := FDDLCToLength(a.DLC)
+	return a.ID == b.ID && a.MsgType == b.MsgType && a.DLC == b.DLC && bytes.Equal(a.Data[:length], b.Data[:length])
+}
+
+// Returns a description of the differences of two messages, empty if they are equal (see Equal())
+func Diff(a, b *TPCANMsg) string {
+	var diffs []string
+	if a.ID != b.ID {
+		diffs = append(diffs, fmt.Sprintf("ID 0x%X != 0x%X", a.ID, b.ID))
+	}
+	if a.MsgType != b.MsgType {
+		diffs = append(diffs, fmt.Sprintf("MsgType 0x%X != 0x%X", a.MsgType, b.MsgType))
+	}
+	if a.DLC != b.DLC {
+		diffs = append(diffs, fmt.Sprintf("DLC %v != %v", a.DLC, b.DLC))
+	}
+	dataA, dataB := a.Data[:a.dataLen()], b.Data[:b.dataLen()]
+	for i := range max(len(dataA), len(dataB)) {
+		switch {
+		case i >= len(dataA):
+			diffs = append(diffs, fmt.Sprintf("Data[%v] missing != 0x%02X", i, dataB[i]))
+		case i >= len(dataB):
+			diffs = append(diffs, fmt.Sprintf("Data[%v] 0x%02X != missing", i, dataA[i]))
+		case dataA[i] != dataB[i]:
+			diffs = append(diffs, fmt.Sprintf("Data[%v] 0x%02X != 0x%02X", i, dataA[i], dataB[i]))
+		}
+	}
+	return strings.Join(diffs, ", ")
+}
+
+// Returns the DLC code of a CAN FD message with the given data length
+// length: Data length in bytes, must be one of 0..8, 12, 16, 20, 24, 32, 48 or 64
+func FDLengthToDLC(length int) (uint8, error) {
+	if length >= 0 && length <= LENGTH_DATA_CAN_MESSAGE {
+		return uint8(length), nil
+	}
+	for dlc, l := range fdDLCLengths {
+		if l == length {
+			return uint8(dlc), nil
+		}
+	}
+	return 0, fmt.Errorf("data length of %v bytes is not representable in a CAN FD message", length)
+}
+
+// Returns the data length in bytes of a CAN FD message with the given DLC code
+func FDDLCToLength(dlc uint8) int {
+	return fdDLCLengths[min(dlc, uint8(len(fdDLCLengths)-1))]
+}
+
+// data lengths in bytes of CAN FD messages indexed by DLC code
+var fdDLCLengths = [...]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 12, 16, 20, 24, 32, 48, 64}
+
+// Returns the worst case amount of bits of a classic CAN frame on the bus including stuff bits and interframe space
+// extended: Frame has a 29-bit identifier
+// dataLen: Amount of data bytes (0..8), zero for RTR frames
+func FrameBitsWorstCase(extended bool, dataLen int) int {
+	stuffable := 34 // bits from start of frame to end of CRC without data which are subject to bit stuffing
+	if extended {
+		stuffable = 54
+	}
+	stuffable += 8 * dataLen
+	return stuffable + 13 + (stuffable-1)/4
+}
+
+// Returns the worst case amount of bits of a CAN FD frame on the bus, split into the bits sent with the nominal and
+// the bits sent with the data bit rate
+// extended: Frame has a 29-bit identifier
+// brs: Frame switches to the data bit rate (PCAN_MESSAGE_BRS), otherwise all bits are sent with the nominal bit rate
+// dataLen: Amount of data bytes (0..64)
+// Note: Stuff bits up to the data are dynamic, the stuff count and CRC have a fixed stuff bit every four bits
+func FrameBitsWorstCaseFD(extended bool, brs bool, dataLen int) (int, int) {
+	arbitration := 17 // SOF, identifier, RRS, IDE, FDF, res and BRS
+	if extended {
+		arbitration = 36
+	}
+	arbitration += (arbitration - 1) / 4
+
+	crc := 17
+	if dataLen > 16 {
+		crc = 21
+	}
+	// ESI, DLC and data with dynamic stuff bits followed by stuff count, CRC and their fixed stuff bits
+	dynamic := 1 + 4 + 8*dataLen
+	data := dynamic + dynamic/4 + 4 + crc + 1 + (4+crc)/4
+	// CRC delimiter, ACK, EOF and interframe space
+	nominal := arbitration + 13
+
+	if !brs {
+		return nominal + data, 0
+	}
+	return nominal, data
+}
+
+// Returns the worst case time a classic CAN frame occupies the bus including stuff bits and interframe space
+// msg: Frame to send, RTR frames carry no data
+// nominalBitrate: Bit rate of the bus in bits per second
+func FrameTime(msg *TPCANMsg, nominalBitrate uint32) time.Duration {
+	bits := FrameBitsWorstCase(msg.MsgType&PCAN_MESSAGE_EXTENDED != 0, msg.dataLen())
+	return bitsTime(bits, nominalBitrate)
+}
+
+// Returns the worst case time a CAN FD frame occupies the bus including stuff bits and interframe space
+// msg: Frame to send, a frame without PCAN_MESSAGE_FD is treated as classic frame
+// nominalBitrate: Bit rate of the arbitration phase in bits per second
+// dataBitrate: Bit rate of the data phase in bits per second, only used if the frame has PCAN_MESSAGE_BRS set
+func FrameTimeFD(msg *TPCANMsgFD, nominalBitrate uint32, dataBitrate uint32) time.Duration {
+	extended := msg.MsgType&PCAN_MESSAGE_EXTENDED != 0
+	if msg.MsgType&PCAN_MESSAGE_FD == 0 {
+		dataLen := int(min(msg.DLC, LENGTH_DATA_CAN_MESSAGE))
+		if msg.MsgType&PCAN_MESSAGE_RTR != 0 {
+			dataLen = 0
+		}
+		return bitsTime(FrameBitsWorstCase(extended, dataLen), nominalBitrate)
+	}
+
+	nominalBits, dataBits := FrameBitsWorstCaseFD(extended, msg.MsgType&PCAN_MESSAGE_BRS != 0, FDDLCToLength(msg.DLC))
+	return bitsTime(nominalBits, nominalBitrate) + bitsTime(dataBits, dataBitrate)
+}
+
+// returns the time needed to send an amount of bits with a bit rate, rounded up to full nanoseconds
+func bitsTime(bits int, bitrate uint32) time.Duration {
+	if bits == 0 || bitrate == 0 {
+		return 0
+	}
+	return time.Duration((uint64(bits)*uint64(time.Second) + uint64(bitrate) - 1) / uint64(bitrate))
+}
+
+// Returns the bit rate in bits per second of a predefined baud rate register value
+func BaudrateBitsPerSecond(baudRate TPCANBaudrate) (uint32, bool) {
+	bitRate, ok := baudrateBitRates[baudRate]
+	return bitRate, ok
+}
+
+// bit rates in bits per second of the predefined baud rate register values
+var baudrateBitRates = map[TPCANBaudrate]uint32{
+	PCAN_BAUD_1M:   1000000,
+	PCAN_BAUD_800K: 800000,
+	PCAN_BAUD_500K: 500000,
+	PCAN_BAUD_250K: 250000,
+	PCAN_BAUD_125K: 125000,
+	PCAN_BAUD_100K: 100000,
+	PCAN_BAUD_95K:  95238,
+	PCAN_BAUD_83K:  83333,
+	PCAN_BAUD_50K:  50000,
+	PCAN_BAUD_47K:  47619,
+	PCAN_BAUD_33K:  33333,
+	PCAN_BAUD_20K:  20000,
+	PCAN_BAUD_10K:  10000,
+	PCAN_BAUD_5K:   5000,
+}
+
+// creates a zero padded classic CAN message
+func newMsg(id TPCANMsgID, extended bool, data []byte) (TPCANMsg, error) {
+	if err := validateID(id, extended); err != nil {
+		return TPCANMsg{}, err
+	}
+	if len(data) > LENGTH_DATA_CAN_MESSAGE {
+		return TPCANMsg{}, fmt.Errorf("data length of %v bytes exceeds maximum of %v bytes", len(data), LENGTH_DATA_CAN_MESSAGE)
+	}
+	msg := TPCANMsg{ID: id, MsgType: msgTypeFor(extended), DLC: uint8(len(data))}
+	copy(msg.Data[:], data)
+	return msg, nil
+}
+
+// returns the given amount of bytes at the offset if they are part of the valid data
+func (m *TPCANMsg) payload(offset int, size int) ([]byte, error) {
+	dlc := int(min(m.DLC, LENGTH_DATA_CAN_MESSAGE))
+	if offset < 0 || offset+size > dlc {
+		return nil, fmt.Errorf("reading %v bytes at offset %v exceeds DLC of %v", size, offset, m.DLC)
+	}
+	return m.Data[offset : offset+size], nil
+}
+
+// increases the DLC to include the given amount of bytes at the offset
+func (m *TPCANMsg) grow(offset int, size int) error {
+	if offset < 0 || offset+size > LENGTH_DATA_CAN_MESSAGE {
+		return fmt.Errorf("writing %v bytes at offset %v exceeds maximum of %v bytes", size, offset, LENGTH_DATA_CAN_MESSAGE)
+	}
+	m.DLC = max(m.DLC, uint8(offset+size))
+	return nil
+}
+
+// returns the byte order for big or little endian values
+func byteOrder(be bool) binary.ByteOrder {
+	if be {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
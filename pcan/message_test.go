@@ -0,0 +1,41 @@
+package pcan
+
+import (
+	"testing"
+)
+
+/* Tests of the message helpers. */
+
+func TestNormalizeID(t *testing.T) {
+	tests := []struct {
+		id      TPCANMsgID
+		msgType TPCANMessageType
+		want    TPCANMsgID
+	}{
+		{0x123, PCAN_MESSAGE_STANDARD, 0x123},
+		{0x7FF, PCAN_MESSAGE_STANDARD, 0x7FF},
+		{0x923, PCAN_MESSAGE_STANDARD, 0x123},
+		{0xFFFFF923, PCAN_MESSAGE_STANDARD, 0x123},
+		{0x80000123, PCAN_MESSAGE_RTR, 0x123},
+		{0x80000123, PCAN_MESSAGE_STANDARD | PCAN_MESSAGE_ECHO, 0x123},
+		{0x1FFFFFFF, PCAN_MESSAGE_EXTENDED, 0x1FFFFFFF},
+		{0xE0000923, PCAN_MESSAGE_EXTENDED, 0x923},
+		{0xFFFFFFFF, PCAN_MESSAGE_EXTENDED | PCAN_MESSAGE_RTR, 0x1FFFFFFF},
+		{0xFFFFFFFF, PCAN_MESSAGE_STATUS, 0xFFFFFFFF},
+		{0x80000008, PCAN_MESSAGE_ERRFRAME, 0x80000008},
+	}
+	for _, test := range tests {
+		if got := normalizeID(test.id, test.msgType); got != test.want {
+			t.Errorf("normalizeID(0x%X, 0x%X) = 0x%X, want 0x%X", test.id, test.msgType, got, test.want)
+		}
+	}
+}
+
+func TestReadMasksStandardID(t *testing.T) {
+	stubRead(t, dataFrame(0xF923, 1))
+
+	bus := &TPCANBus{Handle: PCAN_USBBUS1}
+	if _, msg, _, _ := bus.Read(); msg == nil || msg.ID != 0x123 {
+		t.Errorf("Read() = %+v, want the identifier masked to 0x123", msg)
+	}
+}
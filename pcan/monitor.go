@@ -0,0 +1,207 @@
+package pcan
+
+import (
+	"bytes"
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+/* Monitoring of a bus dispatching received messages to registered callbacks. */
+
+const (
+	monitorReadTimeout = 100 // timeout in milliseconds of a single read, limits the reaction time on a cancelled context
+	staleFactor        = 3   // a periodic message is stale if it was not received for this multiple of its period
+)
+
+// Reads messages from a bus and dispatches them to registered callbacks
+type Monitor struct {
+	bus *TPCANBus
+
+	mutex          sync.Mutex
+	changeHandlers map[TPCANMsgID][]func(old, new *TPCANMsg) // callbacks fired on payload changes
+	lastPayload    map[TPCANMsgID]TPCANMsg                   // last message of IDs with change callbacks
+	watches        map[TPCANMsgID]*timeoutWatch              // staleness watches of periodic messages
+	pending        watchHeap                                 // watches not fired yet, ordered by their deadline
+}
+
+// staleness watch of a periodic message
+type timeoutWatch struct {
+	id        TPCANMsgID
+	period    time.Duration
+	deadline  time.Time // time at which the message is considered stale
+	index     int       // position in the pending heap, -1 if the callback already fired for the current outage
+	onTimeout func(TPCANMsgID)
+}
+
+// min-heap of watches ordered by their deadline, implements heap.Interface
+type watchHeap []*timeoutWatch
+
+func (h watchHeap) Len() int           { return len(h) }
+func (h watchHeap) Less(i, j int) bool { return h[i].deadline.Before(h[j].deadline) }
+func (h watchHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *watchHeap) Push(x any) {
+	watch := x.(*timeoutWatch)
+	watch.index = len(*h)
+	*h = append(*h, watch)
+}
+
+func (h *watchHeap) Pop() any {
+	old := *h
+	watch := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	watch.index = -1
+	return watch
+}
+
+// Creates a monitor for an already initialized bus
+func NewMonitor(bus *TPCANBus) *Monitor {
+	return &Monitor{
+		bus:            bus,
+		changeHandlers: make(map[TPCANMsgID][]func(old, new *TPCANMsg)),
+		lastPayload:    make(map[TPCANMsgID]TPCANMsg),
+		watches:        make(map[TPCANMsgID]*timeoutWatch)}
+}
+
+// Registers a callback fired when the payload of a message changes
+// id: The message identifier to watch
+// cb: Callback receiving the previous and the new message, old is nil for the first received message
+func (m *Monitor) OnChange(id TPCANMsgID, cb func(old, new *TPCANMsg)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.changeHandlers[id] = append(m.changeHandlers[id], cb)
+}
+
+// Registers a callback fired when a periodic message was not received for three times its expected period
+// id: The message identifier to watch, an existing watch of the identifier is replaced
+// expectedPeriod: Period in which the message is expected
+// onTimeout: Callback fired once per outage, again after the message was received and stopped again
+// Note: All watches are checked by the goroutine running Run(), no timer or goroutine per watch is used. The watches
+// are ordered by their deadline, so only expired watches are visited.
+func (m *Monitor) WatchTimeout(id TPCANMsgID, expectedPeriod time.Duration, onTimeout func(TPCANMsgID)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.removeWatch(id)
+	watch := &timeoutWatch{
+		id:        id,
+		period:    expectedPeriod,
+		deadline:  time.Now().Add(staleFactor * expectedPeriod),
+		onTimeout: onTimeout}
+	m.watches[id] = watch
+	heap.Push(&m.pending, watch)
+}
+
+// Removes the staleness watch of a message identifier
+func (m *Monitor) UnwatchTimeout(id TPCANMsgID) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.removeWatch(id)
+}
+
+// removes a watch from the map and the pending heap, the mutex must be held
+func (m *Monitor) removeWatch(id TPCANMsgID) {
+	if watch, ok := m.watches[id]; ok {
+		if watch.index >= 0 {
+			heap.Remove(&m.pending, watch.index)
+		}
+		delete(m.watches, id)
+	}
+}
+
+// Reads messages from the bus and dispatches them until ctx is cancelled or a read error occurs
+// Note: Callbacks are called from the goroutine running this function
+func (m *Monitor) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		// wait at most until the next watch expires
+		timeout := time.Duration(monitorReadTimeout) * time.Millisecond
+		if next, ok := m.checkTimeouts(time.Now()); ok {
+			timeout = min(timeout, time.Until(next))
+		}
+
+		_, msg, _, err := m.bus.ReadWithTimeout(int(max(timeout.Milliseconds(), 1)))
+		if err != nil {
+			return err
+		}
+		if msg != nil {
+			m.dispatch(msg)
+		}
+	}
+}
+
+// fires the callbacks of expired watches and returns the next deadline of a watch not fired yet
+func (m *Monitor) checkTimeouts(now time.Time) (time.Time, bool) {
+	var expired []*timeoutWatch
+
+	m.mutex.Lock()
+	for len(m.pending) > 0 && !now.Before(m.pending[0].deadline) {
+		expired = append(expired, heap.Pop(&m.pending).(*timeoutWatch))
+	}
+	var next time.Time
+	found := len(m.pending) > 0
+	if found {
+		next = m.pending[0].deadline
+	}
+	m.mutex.Unlock()
+
+	for _, watch := range expired {
+		watch.onTimeout(watch.id)
+	}
+	return next, found
+}
+
+// dispatches a received message to all registered callbacks
+func (m *Monitor) dispatch(msg *TPCANMsg) {
+	m.refreshWatch(msg.ID)
+	m.dispatchChange(msg)
+}
+
+// moves the deadline of a watched message as it was received
+func (m *Monitor) refreshWatch(id TPCANMsgID) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if watch, ok := m.watches[id]; ok {
+		watch.deadline = time.Now().Add(staleFactor * watch.period)
+		if watch.index >= 0 {
+			heap.Fix(&m.pending, watch.index)
+		} else {
+			heap.Push(&m.pending, watch)
+		}
+	}
+}
+
+// fires change callbacks if the payload differs from the last received message with the same ID
+func (m *Monitor) dispatchChange(msg *TPCANMsg) {
+	m.mutex.Lock()
+	handlers := m.changeHandlers[msg.ID]
+	if len(handlers) == 0 {
+		m.mutex.Unlock()
+		return
+	}
+	prev, seen := m.lastPayload[msg.ID]
+	m.lastPayload[msg.ID] = *msg
+	m.mutex.Unlock()
+
+	if seen && prev.DLC == msg.DLC && bytes.Equal(prev.Data[:min(prev.DLC, LENGTH_DATA_CAN_MESSAGE)], msg.Data[:min(msg.DLC, LENGTH_DATA_CAN_MESSAGE)]) {
+		return
+	}
+	var old *TPCANMsg
+	if seen {
+		old = &prev
+	}
+	for _, cb := range handlers {
+		cb(old, msg)
+	}
+}
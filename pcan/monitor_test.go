@@ -0,0 +1,53 @@
+package pcan
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+/* Tests of the staleness watches of the monitor. */
+
+func TestMonitorCheckTimeouts(t *testing.T) {
+	monitor := NewMonitor(&TPCANBus{})
+	var fired []TPCANMsgID
+	onTimeout := func(id TPCANMsgID) { fired = append(fired, id) }
+
+	start := time.Now()
+	monitor.WatchTimeout(0x300, 30*time.Millisecond, onTimeout) // stale after 90 ms
+	monitor.WatchTimeout(0x100, 10*time.Millisecond, onTimeout) // stale after 30 ms
+	monitor.WatchTimeout(0x200, 20*time.Millisecond, onTimeout) // stale after 60 ms
+	monitor.WatchTimeout(0x400, time.Millisecond, onTimeout)
+	monitor.UnwatchTimeout(0x400)
+
+	next, ok := monitor.checkTimeouts(start)
+	if !ok || next.Before(start.Add(30*time.Millisecond)) || !next.Before(start.Add(60*time.Millisecond)) {
+		t.Fatalf("next deadline = %v, %v, want the deadline of 0x100", next.Sub(start), ok)
+	}
+	if len(fired) != 0 {
+		t.Fatalf("fired %#x before any deadline", fired)
+	}
+
+	// everything up to 0x200 expired, each watch fires once per outage
+	_, _ = monitor.checkTimeouts(start.Add(time.Second / 15))
+	_, _ = monitor.checkTimeouts(start.Add(time.Second / 15))
+	if !slices.Equal(fired, []TPCANMsgID{0x100, 0x200}) {
+		t.Errorf("fired = %#x, want 0x100 and 0x200 in deadline order", fired)
+	}
+
+	// receiving a message arms its watch again
+	monitor.refreshWatch(0x100)
+	next, ok = monitor.checkTimeouts(time.Now())
+	if !ok || next != monitor.watches[0x100].deadline {
+		t.Errorf("next deadline = %v, %v, want the refreshed deadline of 0x100", next.Sub(start), ok)
+	}
+
+	fired = nil
+	next, ok = monitor.checkTimeouts(start.Add(time.Hour))
+	if ok {
+		t.Errorf("next deadline = %v after all watches fired", next.Sub(start))
+	}
+	if !slices.Equal(fired, []TPCANMsgID{0x100, 0x300}) {
+		t.Errorf("fired = %#x, want 0x100 and 0x300 in deadline order", fired)
+	}
+}
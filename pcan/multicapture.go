@@ -0,0 +1,110 @@
+package pcan
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+/* Synchronized capturing of several PCAN channels on a common timebase. */
+
+const (
+	captureReadBudget = 10 * time.Millisecond // maximum time a single channel is read per pass
+	captureReadLimit  = 1024                  // maximum amount of messages read from a single channel per pass
+)
+
+// A message received by a MultiCapture
+type CapturedFrame struct {
+	Handle    TPCANHandle    // Channel the message was received on
+	Msg       TPCANMsg       // Received message
+	Timestamp TPCANTimestamp // Driver timestamp of the channel
+	Time      time.Duration  // Normalized time since start of the capture, comparable between all channels
+}
+
+// Captures several PCAN channels and merges their messages into a single time ordered stream
+type MultiCapture struct {
+	buses    []*TPCANBus
+	errMutex sync.Mutex
+	err      error
+}
+
+// reference point of a channel mapping its driver timestamps to the common timebase
+type captureRef struct {
+	driverMicros uint64        // driver timestamp of the first message received on the channel
+	offset       time.Duration // time since capture start at which the first message was received
+}
+
+// Creates a capture for the given already initialized buses
+func NewMultiCapture(buses ...*TPCANBus) *MultiCapture {
+	return &MultiCapture{buses: buses}
+}
+
+// Starts capturing all buses until ctx is cancelled or a read error occurs
+// Note: The returned channel is closed when capturing stops, Err() returns the error which stopped capturing.
+// The first message of every channel is used as reference to map the channel's driver timestamps to the common timebase.
+func (m *MultiCapture) Stream(ctx context.Context) <-chan CapturedFrame {
+	out := make(chan CapturedFrame, 64)
+	go m.run(ctx, out)
+	return out
+}
+
+// Returns the error which stopped capturing, nil if stopped by the context or still capturing
+// Note: The error is final once the channel returned by Stream() is closed
+func (m *MultiCapture) Err() error {
+	m.errMutex.Lock()
+	defer m.errMutex.Unlock()
+	return m.err
+}
+
+// reads all buses round-robin and emits the merged messages of every pass sorted by time
+// Note: Every channel is read for at most captureReadBudget or captureReadLimit messages per pass, so a busy channel
+// can not hold back the others
+func (m *MultiCapture) run(ctx context.Context, out chan<- CapturedFrame) {
+	defer close(out)
+
+	start := time.Now()
+	refs := make([]*captureRef, len(m.buses))
+
+	for {
+		var frames []CapturedFrame
+		for i, bus := range m.buses {
+			msgs, timestamps, err := bus.ReadFor(captureReadBudget, captureReadLimit)
+			if err != nil {
+				m.errMutex.Lock()
+				m.err = err
+				m.errMutex.Unlock()
+				return
+			}
+			for j := range msgs {
+				micros := timestamps[j].TotalMicros()
+				if refs[i] == nil {
+					refs[i] = &captureRef{driverMicros: micros, offset: time.Since(start)}
+				}
+				frames = append(frames, CapturedFrame{
+					Handle:    bus.Handle,
+					Msg:       msgs[j],
+					Timestamp: timestamps[j],
+					Time:      refs[i].offset + time.Duration(int64(micros)-int64(refs[i].driverMicros))*time.Microsecond})
+			}
+		}
+
+		sort.SliceStable(frames, func(a, b int) bool { return frames[a].Time < frames[b].Time })
+		for _, frame := range frames {
+			select {
+			case out <- frame:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		if len(frames) == 0 {
+			time.Sleep(DEFAULT_POLL_INTERVAL)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package pcan
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+/* Tests of the synchronized capturing of several channels. */
+
+func TestMultiCaptureBusyChannel(t *testing.T) {
+	// the first channel receives far more messages than a single pass reads, the second one a single message
+	const busyFrames = 20 * captureReadLimit
+	busyRead, quietRead := 0, false
+	oldRead := apiRead
+	apiRead = func(handle TPCANHandle) (TPCANStatus, TPCANMsg, TPCANTimestamp, error) {
+		if handle == PCAN_USBBUS2 && !quietRead {
+			quietRead = true
+			frame := dataFrame(0x200, 2)
+			return frame.status, frame.msg, frame.timestamp, nil
+		}
+		if handle == PCAN_USBBUS1 && busyRead < busyFrames {
+			busyRead++
+			frame := dataFrame(0x100, 1)
+			return frame.status, frame.msg, frame.timestamp, nil
+		}
+		return PCAN_ERROR_QRCVEMPTY, TPCANMsg{}, TPCANTimestamp{}, nil
+	}
+	t.Cleanup(func() { apiRead = oldRead })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := NewMultiCapture(&TPCANBus{Handle: PCAN_USBBUS1}, &TPCANBus{Handle: PCAN_USBBUS2}).Stream(ctx)
+	busyEmitted := 0
+	for frame := range stream {
+		if frame.Handle == PCAN_USBBUS2 {
+			break
+		}
+		busyEmitted++
+	}
+	cancel()
+	for range stream {
+	}
+	if busyEmitted > captureReadLimit {
+		t.Errorf("message of the quiet channel was emitted after %v messages of the busy channel, want at most %v",
+			busyEmitted, captureReadLimit)
+	}
+}
+
+func TestMultiCaptureErr(t *testing.T) {
+	errRead := errors.New("device removed")
+	stubRead(t, dataFrame(0x100, 1), stubFrame{status: PCAN_ERROR_ILLHW, err: errRead})
+
+	capture := NewMultiCapture(&TPCANBus{Handle: PCAN_USBBUS1})
+	stream := capture.Stream(context.Background())
+	// Err() may be called while capturing
+	running := make(chan error, 1)
+	go func() { running <- capture.Err() }()
+	for range stream {
+	}
+	<-running
+	if err := capture.Err(); !errors.Is(err, errRead) {
+		t.Errorf("Err() = %v, want %v", err, errRead)
+	}
+}
@@ -0,0 +1,88 @@
+package pcan
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+/* Fluent building of message payloads. */
+
+// Assembles a message payload from values appended one after another
+// Note: Appending beyond the maximum length records an error returned by Bytes(), further values are ignored
+type PayloadBuilder struct {
+	data   []byte
+	maxLen int
+	err    error
+}
+
+// Creates a builder for the payload of a classic CAN message (up to 8 bytes)
+func NewPayload() *PayloadBuilder {
+	return &PayloadBuilder{data: make([]byte, 0, LENGTH_DATA_CAN_MESSAGE), maxLen: LENGTH_DATA_CAN_MESSAGE}
+}
+
+// Creates a builder for the payload of a CAN FD message
+// maxLen: Maximum payload length, must be a valid CAN FD data length (0..8, 12, 16, 20, 24, 32, 48 or 64)
+func NewPayloadFD(maxLen int) (*PayloadBuilder, error) {
+	if _, err := FDLengthToDLC(maxLen); err != nil {
+		return nil, err
+	}
+	return &PayloadBuilder{data: make([]byte, 0, maxLen), maxLen: maxLen}, nil
+}
+
+// Appends a single byte
+func (b *PayloadBuilder) AddByte(value byte) *PayloadBuilder {
+	return b.AddBytes(value)
+}
+
+// Appends several bytes
+func (b *PayloadBuilder) AddBytes(values ...byte) *PayloadBuilder {
+	if b.err != nil {
+		return b
+	}
+	if len(b.data)+len(values) > b.maxLen {
+		b.err = fmt.Errorf("appending %v bytes to a payload of %v bytes exceeds maximum of %v bytes", len(values), len(b.data), b.maxLen)
+		return b
+	}
+	b.data = append(b.data, values...)
+	return b
+}
+
+// Appends an uint16 in big endian byte order
+func (b *PayloadBuilder) AddUint16BE(value uint16) *PayloadBuilder {
+	return b.AddBytes(binary.BigEndian.AppendUint16(nil, value)...)
+}
+
+// Appends an uint16 in little endian byte order
+func (b *PayloadBuilder) AddUint16LE(value uint16) *PayloadBuilder {
+	return b.AddBytes(binary.LittleEndian.AppendUint16(nil, value)...)
+}
+
+// Appends an uint32 in big endian byte order
+func (b *PayloadBuilder) AddUint32BE(value uint32) *PayloadBuilder {
+	return b.AddBytes(binary.BigEndian.AppendUint32(nil, value)...)
+}
+
+// Appends an uint32 in little endian byte order
+func (b *PayloadBuilder) AddUint32LE(value uint32) *PayloadBuilder {
+	return b.AddBytes(binary.LittleEndian.AppendUint32(nil, value)...)
+}
+
+// Returns the amount of bytes appended so far
+func (b *PayloadBuilder) Len() int {
+	return len(b.data)
+}
+
+// Returns a copy of the assembled payload or the first error which occurred while appending
+func (b *PayloadBuilder) Bytes() ([]byte, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	return append([]byte(nil), b.data...), nil
+}
+
+// Removes all appended bytes and a recorded error, the maximum length is kept
+func (b *PayloadBuilder) Reset() *PayloadBuilder {
+	b.data = b.data[:0]
+	b.err = nil
+	return b
+}
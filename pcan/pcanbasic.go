@@ -1,14 +1,19 @@
 package pcan
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
-	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 	"unsafe"
 )
 
-/* This file is the windows specific implementation for handling the PCAN driver. */
+/* This file is the implementation for handling the PCAN driver, the parts depending on the operating system are
+in pcanbasic_windows.go and pcanbasic_other.go. */
 
 // PCAN Bus interface
 type TPCANBus struct {
@@ -17,7 +22,27 @@ type TPCANBus struct {
 	HWType    TPCANType     // only for non plug´n´play devices and currently not used
 	IOPort    uint32        // only for non plug´n´play devices and currently not used
 	Interrupt uint16        // only for non plug´n´play devices and currently not used
-	recvEvent syscall.Handle
+	recvEvent eventHandle
+
+	recvEventExternal bool         // recvEvent was provided by the caller with SetReceiveEvent() and is never closed by this package
+	connMutex         sync.RWMutex // held for writing while the channel is initialized again or its receive event changes, for reading by reads
+
+	idAllowlist    atomic.Pointer[idSet]         // software allowlist applied on received messages, nil if disabled
+	softwareFilter atomic.Pointer[Filter]        // software filter applied on received messages, nil if disabled
+	dlcClamped     atomic.Uint64                 // amount of received classic messages with a DLC above 8
+	rxOverflows    atomic.Uint64                 // amount of reads reporting a receive overflow
+	snapshot       atomic.Pointer[SnapshotCache] // cache updated with every received data message, nil if disabled
+	timeRef        atomic.Pointer[timeReference] // mapping of the device timer to host time, nil until a message was received
+
+	pollInterval time.Duration // sleep between two reads when polling, zero selects DEFAULT_POLL_INTERVAL
+	clock        Clock         // source of time when polling, nil selects the real clock
+	isShutdown   atomic.Bool   // set by Shutdown() and unset when the channel is initialized again
+
+	traceStop chan struct{} // stops the pruning of trace files, nil if no pruning is running
+
+	echoMutex     sync.Mutex
+	pendingEchoes []pendingEcho // messages sent by WriteWithMarker and WriteConfirmed waiting for their echo, oldest first
+	unread        []unreadFrame // messages read by WriteConfirmed while waiting for an echo, returned first by Read()
 }
 
 // PCAN Bus interface for CANFD channels
@@ -27,29 +52,107 @@ type TPCANBusFD struct {
 	// TODO fill with FD parameter and other necessary stuff
 }
 
+// procedure of the PCAN driver, the arguments are passed as in the C declaration of the PCAN-Basic api
+type apiProc struct {
+	call func(a ...uintptr) (r1, r2 uintptr, lastErr error)
+}
+
+// Calls the procedure, pointers converted to uintptr in the arguments are moved to the heap and kept alive until it returns
+// Note: Must be called directly and not through a function value, only then the compiler applies uintptrescapes
+//
+//go:uintptrescapes
+func (p *apiProc) Call(a ...uintptr) (r1, r2 uintptr, lastErr error) {
+	return p.call(a...)
+}
+
+// result of waiting for the receive event
+type waitResult int
+
+const (
+	waitSignaled waitResult = iota // the event was signaled
+	waitTimeout                    // the timeout elapsed before the event was signaled
+	waitFailed                     // waiting failed or the event was abandoned
+)
+
+// timeout waiting for an event without limit
+const infiniteTimeout uint32 = 0xFFFFFFFF
+
 // api procedures
 var (
-	pcanAPIHandle         *syscall.DLL  = nil // procedure handle for PCAN driver
-	pHandleInitialize     *syscall.Proc = nil
-	pHandleInitializeFD   *syscall.Proc = nil
-	pHandleUninitialize   *syscall.Proc = nil
-	pHandleReset          *syscall.Proc = nil
-	pHandleGetStatus      *syscall.Proc = nil
-	pHandleRead           *syscall.Proc = nil
-	pHandleReadFD         *syscall.Proc = nil
-	pHandleWrite          *syscall.Proc = nil
-	pHandleWriteFD        *syscall.Proc = nil
-	pHandleFilterMessages *syscall.Proc = nil
-	pHandleGetValue       *syscall.Proc = nil
-	pHandleSetValue       *syscall.Proc = nil
-	pHandleGetErrorText   *syscall.Proc = nil
-	pHandleLookUpChannel  *syscall.Proc = nil
+	pHandleInitialize     *apiProc = nil
+	pHandleInitializeFD   *apiProc = nil
+	pHandleUninitialize   *apiProc = nil
+	pHandleReset          *apiProc = nil
+	pHandleGetStatus      *apiProc = nil
+	pHandleRead           *apiProc = nil
+	pHandleReadFD         *apiProc = nil
+	pHandleWrite          *apiProc = nil
+	pHandleWriteFD        *apiProc = nil
+	pHandleFilterMessages *apiProc = nil
+	pHandleGetValue       *apiProc = nil
+	pHandleSetValue       *apiProc = nil
+	pHandleGetErrorText   *apiProc = nil
+	pHandleLookUpChannel  *apiProc = nil
 
 	apiLoaded bool = false // indicates if the api was loaded already, set by LoadApi() and unset by UnloadApi()
-	hasEvents bool = false
+
+	// If set to true, every initialized channel gets a receive event and ReadWithTimeout() waits for it instead of polling.
+	// Channels for which the event can not be created or registered are polled. Set it before channels are initialized.
+	UseReceiveEvents bool = true
+
+	// If set to true, initializing a channel fails with ErrAPINotLoaded until LoadAPI() was called explicitly.
+	// By default the api is loaded implicitly by the first initialization. Set it before any channel is used.
+	RequireExplicitLoad bool = false
+
+	initializedBuses   = map[TPCANHandle]*TPCANBus{}   // buses of all channels initialized by this package, guarded by initializedBusesMu
+	initializedFDBuses = map[TPCANHandle]*TPCANBusFD{} // buses of all FD channels initialized by this package, guarded by initializedBusesMu
+	initializedBusesMu sync.Mutex
 )
 
+// api calls reading the receive queue and event functions used by the buses, replaced by tests
+var (
+	apiRead      = APIRead
+	apiReadFD    = APIReadFD
+	newEvent     = createEvent
+	waitForEvent = waitEvent
+	freeEvent    = closeEvent
+)
+
+// compile time checks of the struct layouts shared with the PCAN driver, building fails on a mismatch for any architecture
+var (
+	_ [unsafe.Sizeof(TPCANMsg{}) - 16]struct{}
+	_ [16 - unsafe.Sizeof(TPCANMsg{})]struct{}
+	_ [unsafe.Offsetof(TPCANMsg{}.Data) - 6]struct{}
+	_ [6 - unsafe.Offsetof(TPCANMsg{}.Data)]struct{}
+	_ [unsafe.Sizeof(TPCANMsgFD{}) - 72]struct{}
+	_ [72 - unsafe.Sizeof(TPCANMsgFD{})]struct{}
+	_ [unsafe.Sizeof(TPCANTimestamp{}) - 8]struct{}
+	_ [8 - unsafe.Sizeof(TPCANTimestamp{})]struct{}
+)
+
+// errors returned by the package
+var (
+	ErrNotSupported     = errors.New("not supported by the PCAN driver or hardware") // Feature is not available with the used driver or hardware
+	ErrNoChannel        = errors.New("no PCAN channel found")                        // No PCAN channel is attached
+	ErrMultipleChannels = errors.New("multiple PCAN channels found")                 // More than one PCAN channel is attached
+
+	ErrAPINotLoaded         = errors.New("PCAN API is not loaded, call LoadAPI() first")                                                            // Api must be loaded explicitly as RequireExplicitLoad is set
+	ErrAlreadyInitialized   = errors.New("PCAN channel is already initialized with other parameters")                                               // Channel was initialized before, use the existing bus or uninitialize it first
+	ErrArchitectureMismatch = fmt.Errorf("PCAN driver architecture mismatch: build a %v binary to match the installed driver", otherArchitecture()) // PCANBasic.dll was built for another architecture than this binary
+	ErrFunctionNotAvailable = errors.New("function is not exported by the installed PCAN driver")                                                   // Driver is too old for the called function, update the driver
+)
+
+// returns the description of the architecture the installed driver most likely has if it is not loadable
+func otherArchitecture() string {
+	if unsafe.Sizeof(uintptr(0)) == 8 {
+		return "32-bit (GOARCH=386)"
+	}
+	return "64-bit (GOARCH=amd64)"
+}
+
 // Loads PCAN API (.ddl) file
+// Note: Only supported on windows, on other operating systems ErrNotSupported is returned
+// Note: Returns ErrArchitectureMismatch if the installed driver is a 32-bit driver used by a 64-bit binary or vice versa
 func LoadAPI() error {
 	var err error = nil
 
@@ -57,72 +160,189 @@ func LoadAPI() error {
 		return nil
 	}
 
-	// evaluate operating system and architecture and select driver file
-	if runtime.GOOS != "windows" {
-		return fmt.Errorf("invalid operating system. change compile option to match %v", runtime.GOOS)
+	if err = openDriver(); err != nil {
+		return err
 	}
 
-	pcanAPIHandle, err = syscall.LoadDLL("PCANBasic.dll")
-	if err != nil || pcanAPIHandle == nil {
-		return err
+	var missing []string
+	for _, function := range apiFunctions {
+		*function.proc = findDriverProc(function.name)
+		if *function.proc == nil && function.core {
+			missing = append(missing, function.name)
+		}
 	}
 
-	pHandleInitialize, _ = pcanAPIHandle.FindProc("CAN_Initialize")
-	pHandleInitializeFD, _ = pcanAPIHandle.FindProc("CAN_InitializeFD")
-	pHandleUninitialize, _ = pcanAPIHandle.FindProc("CAN_Uninitialize")
-	pHandleReset, _ = pcanAPIHandle.FindProc("CAN_Reset")
-	pHandleGetStatus, _ = pcanAPIHandle.FindProc("CAN_GetStatus")
-	pHandleRead, _ = pcanAPIHandle.FindProc("CAN_Read")
-	pHandleReadFD, _ = pcanAPIHandle.FindProc("CAN_ReadFD")
-	pHandleWrite, _ = pcanAPIHandle.FindProc("CAN_Write")
-	pHandleWriteFD, _ = pcanAPIHandle.FindProc("CAN_WriteFD")
-	pHandleFilterMessages, _ = pcanAPIHandle.FindProc("CAN_FilterMessages")
-	pHandleGetValue, _ = pcanAPIHandle.FindProc("CAN_GetValue")
-	pHandleSetValue, _ = pcanAPIHandle.FindProc("CAN_SetValue")
-	pHandleGetErrorText, _ = pcanAPIHandle.FindProc("CAN_GetErrorText")
-	pHandleLookUpChannel, _ = pcanAPIHandle.FindProc("CAN_LookUpChannel")
-
-	apiLoaded = pHandleInitialize != nil && pHandleInitializeFD != nil && pHandleReset != nil && pHandleGetStatus != nil &&
-		pHandleRead != nil && pHandleReadFD != nil && pHandleWrite != nil && pHandleWriteFD != nil && pHandleFilterMessages != nil && pHandleGetValue != nil &&
-		pHandleSetValue != nil && pHandleGetErrorText != nil && pHandleLookUpChannel != nil && pHandleUninitialize != nil
+	apiLoaded = len(missing) == 0
+	if !apiLoaded {
+		return fmt.Errorf("could not load pointers to pcan functions: %v", strings.Join(missing, ", "))
+	}
+	return nil
+}
 
+// functions of the PCAN API, only the core functions are required to load the api
+// Note: Very old drivers miss e.g. the FD functions and CAN_LookUpChannel, calling them returns ErrFunctionNotAvailable
+var apiFunctions = []struct {
+	proc **apiProc
+	name string
+	core bool
+}{
+	{&pHandleInitialize, "CAN_Initialize", true},
+	{&pHandleInitializeFD, "CAN_InitializeFD", false},
+	{&pHandleUninitialize, "CAN_Uninitialize", true},
+	{&pHandleReset, "CAN_Reset", false},
+	{&pHandleGetStatus, "CAN_GetStatus", false},
+	{&pHandleRead, "CAN_Read", true},
+	{&pHandleReadFD, "CAN_ReadFD", false},
+	{&pHandleWrite, "CAN_Write", true},
+	{&pHandleWriteFD, "CAN_WriteFD", false},
+	{&pHandleFilterMessages, "CAN_FilterMessages", false},
+	{&pHandleGetValue, "CAN_GetValue", true},
+	{&pHandleSetValue, "CAN_SetValue", true},
+	{&pHandleGetErrorText, "CAN_GetErrorText", false},
+	{&pHandleLookUpChannel, "CAN_LookUpChannel", false},
+}
+
+// returns ErrAPINotLoaded if the api is not loaded and ErrFunctionNotAvailable if a function is not exported by the loaded driver
+// Note: ErrFunctionNotAvailable is returned together with ErrNotSupported, so both can be checked with errors.Is
+func checkProc(proc *apiProc, name string) error {
 	if !apiLoaded {
-		return errors.New("could not load pointers to pcan functions")
+		return fmt.Errorf("%w: %v", ErrAPINotLoaded, name)
+	}
+	if proc == nil {
+		return fmt.Errorf("%w (%w): %v", ErrFunctionNotAvailable, ErrNotSupported, name)
 	}
 	return nil
 }
 
+// Features of the PCAN API available with the loaded driver
+type Capabilities struct {
+	Loaded        bool     // The api is loaded, all core functions are available
+	FD            bool     // CAN FD channels can be used (CAN_InitializeFD, CAN_ReadFD, CAN_WriteFD)
+	LookUpChannel bool     // Channels can be looked up by their parameters (CAN_LookUpChannel)
+	Reset         bool     // Queues can be reset (CAN_Reset)
+	Status        bool     // The bus status can be read (CAN_GetStatus)
+	Filter        bool     // The range filter can be set (CAN_FilterMessages)
+	ErrorText     bool     // Status descriptions can be read from the driver (CAN_GetErrorText)
+	Missing       []string // Names of the functions not exported by the loaded driver
+}
+
+// Returns which features are available with the loaded driver, all features are unavailable before LoadAPI() was called
+// Note: Calling a function of a missing feature returns ErrFunctionNotAvailable
+func LoadedCapabilities() Capabilities {
+	if !apiLoaded {
+		return Capabilities{}
+	}
+
+	capabilities := Capabilities{
+		Loaded:        true,
+		FD:            pHandleInitializeFD != nil && pHandleReadFD != nil && pHandleWriteFD != nil,
+		LookUpChannel: pHandleLookUpChannel != nil,
+		Reset:         pHandleReset != nil,
+		Status:        pHandleGetStatus != nil,
+		Filter:        pHandleFilterMessages != nil,
+		ErrorText:     pHandleGetErrorText != nil,
+	}
+	for _, function := range apiFunctions {
+		if *function.proc == nil {
+			capabilities.Missing = append(capabilities.Missing, function.name)
+		}
+	}
+	return capabilities
+}
+
+// Loads PCAN API (.ddl) file and retries if the driver is not available yet, e.g. while its service is still starting
+// attempts: Maximum amount of load attempts
+// delay: Wait time between two attempts
+// Note: Only retries if the driver file was not found or is not ready, other errors like ErrArchitectureMismatch fail immediately
+func LoadAPIWithRetry(attempts int, delay time.Duration) error {
+	err := errors.New("no load attempt made")
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			time.Sleep(delay)
+		}
+		err = LoadAPI()
+		if err == nil || !isTransientLoadError(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// windows errors returned while the driver is not installed or not ready yet
+const (
+	errorFileNotFound syscall.Errno = 2
+	errorNotReady     syscall.Errno = 21
+	errorModNotFound  syscall.Errno = 126
+)
+
+// windows errors returned by the driver and by loading it
+const (
+	errorInsufficientBuffer syscall.Errno = 122 // left over by the driver on successful calls
+	errorBadExeFormat       syscall.Errno = 193 // the library was built for another architecture
+)
+
+// checks if loading the driver failed only because it is not available yet
+func isTransientLoadError(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	return errno == errorFileNotFound || errno == errorNotReady || errno == errorModNotFound
+}
+
+// loads the api on first use unless RequireExplicitLoad is set
+func loadAPIImplicitly() error {
+	if apiLoaded {
+		return nil
+	}
+	if RequireExplicitLoad {
+		return ErrAPINotLoaded
+	}
+	return LoadAPI()
+}
+
 // Unloads PCAN API (.ddl) file
+// Note: All channels initialized by this package are uninitialized and their receive events are closed before.
+// Calling UnloadAPI without a loaded api does nothing.
 func UnloadAPI() error {
+	if !driverOpen() {
+		apiLoaded = false
+		return nil
+	}
+
+	// release channels still in use, their buses must not be used afterwards
+	initializedBusesMu.Lock()
+	for _, bus := range initializedBuses {
+		bus.stopTracePruning()
+		bus.connMutex.Lock()
+		_ = bus.closeRecvEvent()
+		bus.connMutex.Unlock()
+	}
+	clear(initializedBuses)
+	clear(initializedFDBuses)
+	initializedBusesMu.Unlock()
+	if pHandleUninitialize != nil {
+		_, _ = APIUninitialize(PCAN_NONEBUS)
+	}
 
 	// reset pointers
-	pHandleInitialize = nil
-	pHandleInitializeFD = nil
-	pHandleUninitialize = nil
-	pHandleReset = nil
-	pHandleGetStatus = nil
-	pHandleRead = nil
-	pHandleReadFD = nil
-	pHandleWrite = nil
-	pHandleWriteFD = nil
-	pHandleFilterMessages = nil
-	pHandleGetValue = nil
-	pHandleSetValue = nil
-	pHandleGetErrorText = nil
-	pHandleLookUpChannel = nil
-	pHandleUninitialize = nil
+	for _, function := range apiFunctions {
+		*function.proc = nil
+	}
 	apiLoaded = false
 
-	err := pcanAPIHandle.Release()
-	return err
+	return releaseDriver()
 }
 
 // API call to iInitializes a basic plugNplay PCAN Channel
 // Channel: The handle of a PCAN Channel
 // baudRate: The speed for the communication (BTR0BTR1 code)
 func APIInitializeBasic(handle TPCANHandle, baudRate TPCANBaudrate) (TPCANStatus, error) {
+	if err := checkProc(pHandleInitialize, "CAN_Initialize"); err != nil {
+		return PCAN_ERROR_ILLOPERATION, err
+	}
 	r, _, errno := pHandleInitialize.Call(uintptr(handle), uintptr(baudRate))
-	return TPCANStatus(r), syscallErr(errno)
+	return TPCANStatus(r), statusErr(TPCANStatus(r), errno)
 }
 
 // API call to initializes a advanced PCAN Channel
@@ -132,8 +352,11 @@ func APIInitializeBasic(handle TPCANHandle, baudRate TPCANBaudrate) (TPCANStatus
 // ioPort: Non-PnP: The I/O address for the parallel port
 // interrupt: Non-PnP: Interrupt number of the parallel port
 func APIInitialize(handle TPCANHandle, baudRate TPCANBaudrate, hwType TPCANType, ioPort uint32, interrupt uint16) (TPCANStatus, error) {
+	if err := checkProc(pHandleInitialize, "CAN_Initialize"); err != nil {
+		return PCAN_ERROR_ILLOPERATION, err
+	}
 	r, _, errno := pHandleInitialize.Call(uintptr(handle), uintptr(baudRate), uintptr(hwType), uintptr(ioPort), uintptr(interrupt))
-	return TPCANStatus(r), syscallErr(errno)
+	return TPCANStatus(r), statusErr(TPCANStatus(r), errno)
 }
 
 // API call to initializes a FD capable PCAN Channel
@@ -148,24 +371,41 @@ func APIInitialize(handle TPCANHandle, baudRate TPCANBaudrate, hwType TPCANType,
 //   - Following Parameters are optional (not used yet): data_ssp_offset, nom_sam
 //   - Example: f_clock=80000000,nom_brp=10,nom_tseg1=5,nom_tseg2=2,nom_sjw=1,data_brp=4,data_tseg1=7,data_tseg2=2,data_sjw=1
 func APIInitializeFD(handle TPCANHandle, bitRateFD TPCANBitrateFD) (TPCANStatus, error) {
-	r, _, errno := pHandleInitializeFD.Call(uintptr(handle), uintptr(unsafe.Pointer(&bitRateFD)))
-	return TPCANStatus(r), syscallErr(errno)
+	if err := checkProc(pHandleInitializeFD, "CAN_InitializeFD"); err != nil {
+		return PCAN_ERROR_ILLOPERATION, err
+	}
+	// the driver expects a null terminated string
+	buffer, err := syscall.BytePtrFromString(string(bitRateFD))
+	if err != nil {
+		return PCAN_ERROR_ILLPARAMVAL, err
+	}
+	r, _, errno := pHandleInitializeFD.Call(uintptr(handle), uintptr(unsafe.Pointer(buffer)))
+	return TPCANStatus(r), statusErr(TPCANStatus(r), errno)
 }
 
 // API call to uninitializes PCAN Channels initialized by CAN_Initialize
 func APIUninitialize(handle TPCANHandle) (TPCANStatus, error) {
+	if err := checkProc(pHandleUninitialize, "CAN_Uninitialize"); err != nil {
+		return PCAN_ERROR_ILLOPERATION, err
+	}
 	r, _, errno := pHandleUninitialize.Call(uintptr(handle))
-	return TPCANStatus(r), syscallErr(errno)
+	return TPCANStatus(r), statusErr(TPCANStatus(r), errno)
 }
 
 // API call to reset the receive and transmit queues of the PCAN Channel
 func APIReset(handle TPCANHandle) (TPCANStatus, error) {
+	if err := checkProc(pHandleReset, "CAN_Reset"); err != nil {
+		return PCAN_ERROR_ILLOPERATION, err
+	}
 	r, _, errno := pHandleReset.Call(uintptr(handle))
-	return TPCANStatus(r), syscallErr(errno)
+	return TPCANStatus(r), statusErr(TPCANStatus(r), errno)
 }
 
 // API call to get the current status of a PCAN Channel
 func APIGetStatus(handle TPCANHandle) (TPCANStatus, error) {
+	if err := checkProc(pHandleGetStatus, "CAN_GetStatus"); err != nil {
+		return PCAN_ERROR_ILLOPERATION, err
+	}
 	r, _, errno := pHandleGetStatus.Call(uintptr(handle))
 	return TPCANStatus(r), syscallErr(errno)
 }
@@ -175,32 +415,45 @@ func APIGetStatus(handle TPCANHandle) (TPCANStatus, error) {
 func APIRead(handle TPCANHandle) (TPCANStatus, TPCANMsg, TPCANTimestamp, error) {
 	var msg TPCANMsg
 	var timestamp TPCANTimestamp
+	if err := checkProc(pHandleRead, "CAN_Read"); err != nil {
+		return PCAN_ERROR_ILLOPERATION, msg, timestamp, err
+	}
 
+	// the driver only writes into these locals during the call, they are returned by value so no caller aliases them
 	r, _, errno := pHandleRead.Call(uintptr(handle), uintptr(unsafe.Pointer(&msg)), uintptr(unsafe.Pointer(&timestamp)))
-	return TPCANStatus(r), msg, timestamp, syscallErr(errno)
+	return TPCANStatus(r), msg, timestamp, statusErr(TPCANStatus(r), errno)
 }
 
 // API call to read a CAN message from the receive queue of a FD capable PCAN Channel
 func APIReadFD(handle TPCANHandle) (TPCANStatus, TPCANMsgFD, TPCANTimestampFD, error) {
 	var msg TPCANMsgFD
 	var timestamp TPCANTimestampFD
+	if err := checkProc(pHandleReadFD, "CAN_ReadFD"); err != nil {
+		return PCAN_ERROR_ILLOPERATION, msg, timestamp, err
+	}
 
 	r, _, errno := pHandleReadFD.Call(uintptr(handle), uintptr(unsafe.Pointer(&msg)), uintptr(unsafe.Pointer(&timestamp)))
-	return TPCANStatus(r), msg, timestamp, syscallErr(errno)
+	return TPCANStatus(r), msg, timestamp, statusErr(TPCANStatus(r), errno)
 }
 
 // API call to transmits a CAN message
 // msg: A Message struct with the message to be sent
 func APIWrite(handle TPCANHandle, msg *TPCANMsg) (TPCANStatus, error) {
+	if err := checkProc(pHandleWrite, "CAN_Write"); err != nil {
+		return PCAN_ERROR_ILLOPERATION, err
+	}
 	r, _, errno := pHandleWrite.Call(uintptr(handle), uintptr(unsafe.Pointer(msg)))
-	return TPCANStatus(r), syscallErr(errno)
+	return TPCANStatus(r), statusErr(TPCANStatus(r), errno)
 }
 
 // API call to transmit a CAN message over a FD capable PCAN Channel
 // msgFD A MessageFD struct with the message to be sent
 func APIWriteFD(handle TPCANHandle, msg *TPCANMsgFD) (TPCANStatus, error) {
+	if err := checkProc(pHandleWriteFD, "CAN_WriteFD"); err != nil {
+		return PCAN_ERROR_ILLOPERATION, err
+	}
 	r, _, errno := pHandleWriteFD.Call(uintptr(handle), uintptr(unsafe.Pointer(msg)))
-	return TPCANStatus(r), syscallErr(errno)
+	return TPCANStatus(r), statusErr(TPCANStatus(r), errno)
 }
 
 // API call to retrieve a PCAN Channel value
@@ -208,9 +461,16 @@ func APIWriteFD(handle TPCANHandle, msg *TPCANMsgFD) (TPCANStatus, error) {
 // Note: Parameters can be present or not according with the kind
 // Note: Parameters can be present or not according with the kind of Hardware (PCAN Channel) being used.
 // If a parameter is not available, a PCAN_ERROR_ILLPARAMTYPE error will be returned
+// Note: The buffer size of well-known parameters is checked first, a mismatch returns ErrBufferSize without calling the driver
 func APIGetValue(handle TPCANHandle, param TPCANParameter, buffer unsafe.Pointer, bufferSize uint32) (TPCANStatus, error) {
+	if err := checkProc(pHandleGetValue, "CAN_GetValue"); err != nil {
+		return PCAN_ERROR_ILLOPERATION, err
+	}
+	if err := checkValueSize(param, buffer, bufferSize); err != nil {
+		return PCAN_ERROR_ILLPARAMVAL, err
+	}
 	r, _, errno := pHandleGetValue.Call(uintptr(handle), uintptr(param), uintptr(buffer), uintptr(bufferSize))
-	return TPCANStatus(r), syscallErr(errno)
+	return TPCANStatus(r), statusErr(TPCANStatus(r), errno)
 }
 
 // API call to configure a PCAN Channel value.
@@ -219,9 +479,16 @@ func APIGetValue(handle TPCANHandle, param TPCANParameter, buffer unsafe.Pointer
 // value: Value of parameter
 // Note: Parameters can be present or not according with the kind of Hardware (PCAN Channel) being used.
 // If a parameter is not available, a PCAN_ERROR_ILLPARAMTYPE error will be returned
+// Note: The buffer size of well-known parameters is checked first, a mismatch returns ErrBufferSize without calling the driver
 func APISetValue(handle TPCANHandle, param TPCANParameter, buffer unsafe.Pointer, bufferSize uint32) (TPCANStatus, error) {
+	if err := checkProc(pHandleSetValue, "CAN_SetValue"); err != nil {
+		return PCAN_ERROR_ILLOPERATION, err
+	}
+	if err := checkValueSize(param, buffer, bufferSize); err != nil {
+		return PCAN_ERROR_ILLPARAMVAL, err
+	}
 	r, _, errno := pHandleSetValue.Call(uintptr(handle), uintptr(param), uintptr(buffer), uintptr(bufferSize))
-	return TPCANStatus(r), syscallErr(errno)
+	return TPCANStatus(r), statusErr(TPCANStatus(r), errno)
 }
 
 // API call to configure the reception filter
@@ -229,8 +496,11 @@ func APISetValue(handle TPCANHandle, param TPCANParameter, buffer unsafe.Pointer
 // toID: The highest CAN ID to be received
 // mode: Message type, Standard (11-bit identifier) or Extended (29-bit identifier)
 func APISetFilter(handle TPCANHandle, fromID TPCANMsgID, toID TPCANMsgID, mode TPCANMode) (TPCANStatus, error) {
+	if err := checkProc(pHandleFilterMessages, "CAN_FilterMessages"); err != nil {
+		return PCAN_ERROR_ILLOPERATION, err
+	}
 	r, _, errno := pHandleFilterMessages.Call(uintptr(handle), uintptr(fromID), uintptr(toID), uintptr(mode))
-	return TPCANStatus(r), syscallErr(errno)
+	return TPCANStatus(r), statusErr(TPCANStatus(r), errno)
 }
 
 // API call to return a descriptive text of a given TPCANStatus error code, in any desired language
@@ -238,6 +508,9 @@ func APISetFilter(handle TPCANHandle, fromID TPCANMsgID, toID TPCANMsgID, mode T
 // language: Indicates a 'Primary language ID'
 func APIGetErrorText(status TPCANStatus, language TPCANLanguage) (TPCANStatus, [MAX_LENGHT_STRING_BUFFER]byte, error) {
 	var buffer [MAX_LENGHT_STRING_BUFFER]byte
+	if err := checkProc(pHandleGetErrorText, "CAN_GetErrorText"); err != nil {
+		return PCAN_ERROR_ILLOPERATION, buffer, err
+	}
 
 	r, _, errno := pHandleGetErrorText.Call(uintptr(status), uintptr(language), uintptr(unsafe.Pointer(&buffer)))
 	return TPCANStatus(r), buffer, syscallErr(errno)
@@ -249,7 +522,6 @@ func APIGetErrorText(status TPCANStatus, language TPCANLanguage) (TPCANStatus, [
 func APILookUpChannel(deviceType string, deviceID string, controllerNumber string, ipAdress string) (TPCANStatus, TPCANHandle, error) {
 
 	var sParameters string = ""
-	var foundChannel TPCANHandle
 
 	// merge search parameter
 	if deviceType != "" {
@@ -275,24 +547,43 @@ func APILookUpChannel(deviceType string, deviceID string, controllerNumber strin
 		sParameters += string(LOOKUP_IP_ADDRESS) + "=" + ipAdress
 	}
 
-	r, _, errno := pHandleLookUpChannel.Call(uintptr(unsafe.Pointer(&sParameters)), uintptr(unsafe.Pointer(&foundChannel)))
-	return TPCANStatus(r), foundChannel, syscallErr(errno)
+	return APILookUpChannelRaw(sParameters)
 }
 
-// helper function to handle syscall return value
-func syscallErr(err error) error {
-	if err != nil {
-		errno := err.(syscall.Errno)
-		if errno != 0 {
+// API call to find a PCAN-Basic Channel that matches with the given parameter string
+// parameters: A comma separated string contained pairs of parameter-name/value to be matched within a PCAN-Basic Channel
+func APILookUpChannelRaw(parameters string) (TPCANStatus, TPCANHandle, error) {
+	if err := checkProc(pHandleLookUpChannel, "CAN_LookUpChannel"); err != nil {
+		return PCAN_ERROR_ILLOPERATION, 0, err
+	}
+	var foundChannel TPCANHandle
 
-			// suppress this warning as this is set by PCAN api
-			if errno == syscall.ERROR_INSUFFICIENT_BUFFER {
-				fmt.Printf("pcan api warning: %v\n", errno)
-				return nil
-			}
+	// the driver expects a null terminated string
+	buffer := make([]byte, len(parameters)+1)
+	copy(buffer, parameters)
 
-			return errors.New(errno.Error())
-		}
+	r, _, errno := pHandleLookUpChannel.Call(uintptr(unsafe.Pointer(&buffer[0])), uintptr(unsafe.Pointer(&foundChannel)))
+	return TPCANStatus(r), foundChannel, statusErr(TPCANStatus(r), errno)
+}
+
+// helper function to convert a null terminated string buffer returned by the PCAN api
+func cString(buffer []byte) string {
+	if i := bytes.IndexByte(buffer, 0); i >= 0 {
+		buffer = buffer[:i]
 	}
-	return nil
+	return strings.TrimSpace(string(buffer))
+}
+
+// helper function to handle syscall return value
+// Note: The errno of a call is only meaningful if it is set, the result of the driver is its returned status.
+// ERROR_INSUFFICIENT_BUFFER is left over by the driver on successful calls and therefore ignored.
+func syscallErr(err error) error {
+	var errno syscall.Errno
+	if err == nil || !errors.As(err, &errno) {
+		return err
+	}
+	if errno == 0 || errno == errorInsufficientBuffer {
+		return nil
+	}
+	return errno
 }
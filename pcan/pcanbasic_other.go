@@ -0,0 +1,73 @@
+//go:build !windows
+
+package pcan
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+/* This file is the stub for operating systems other than windows. PEAK provides libpcanbasic.so for linux, but
+loading a shared library without cgo is not possible with the standard library, so LoadAPI() returns ErrNotSupported and
+every api call returns ErrAPINotLoaded. Everything not calling the driver (messages, DBC signals, timing
+calculations) can be used and tested on any operating system. */
+
+// handle of an event signaled by the driver
+type eventHandle = uintptr
+
+// fails as the driver can only be loaded on windows
+func openDriver() error {
+	return fmt.Errorf("%w: loading the PCAN driver on %v/%v", ErrNotSupported, runtime.GOOS, runtime.GOARCH)
+}
+
+// returns a procedure of the loaded driver, never called as the driver can not be loaded
+func findDriverProc(name string) *apiProc {
+	return nil
+}
+
+// returns if the driver library is loaded
+func driverOpen() bool {
+	return false
+}
+
+// releases the driver library
+func releaseDriver() error {
+	return nil
+}
+
+// fails as receive events are only available on windows
+func createEvent() (eventHandle, error) {
+	return 0, ErrNotSupported
+}
+
+// fails as receive events are only available on windows
+func waitEvent(event eventHandle, timeout uint32) (waitResult, error) {
+	return waitFailed, ErrNotSupported
+}
+
+// closes an event created by createEvent(), there is none to close
+func closeEvent(event eventHandle) error {
+	return nil
+}
+
+// helper function to encode a string as null terminated UTF-8 string as expected by the PCAN api
+func encodeAnsiString(value string, buffer []byte) error {
+	if len(buffer) == 0 {
+		return fmt.Errorf("string buffer has a size of zero")
+	}
+	clear(buffer)
+	if strings.IndexByte(value, 0) >= 0 {
+		return fmt.Errorf("invalid string %q: contains a null character", value)
+	}
+	if len(value)+1 > len(buffer) {
+		return fmt.Errorf("string %q exceeds max length of %v bytes including terminator", value, len(buffer))
+	}
+	copy(buffer, value)
+	return nil
+}
+
+// helper function to convert a null terminated string returned by the PCAN api to valid UTF-8
+func decodeAnsiString(buffer []byte) string {
+	return strings.ToValidUTF8(cString(buffer), "?")
+}
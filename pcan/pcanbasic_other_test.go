@@ -0,0 +1,20 @@
+//go:build !windows
+
+package pcan
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLoadAPINotSupported(t *testing.T) {
+	if err := LoadAPI(); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("LoadAPI() = %v, want ErrNotSupported", err)
+	}
+	if apiLoaded || LoadedCapabilities().Loaded {
+		t.Error("api is reported as loaded")
+	}
+	if _, _, err := InitializeBasic(PCAN_USBBUS1, PCAN_BAUD_500K); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("initializing a channel = %v, want ErrNotSupported", err)
+	}
+}
@@ -0,0 +1,97 @@
+package pcan
+
+import (
+	"errors"
+	"testing"
+	"unsafe"
+)
+
+/* Tests of the api calls against stubbed driver procedures, they run on every operating system. */
+
+// replaces a driver procedure for the duration of a test, the api counts as loaded meanwhile
+func stubProc(t *testing.T, proc **apiProc, call func(a ...uintptr) (uintptr, uintptr, error)) {
+	t.Helper()
+	oldProc, oldLoaded := *proc, apiLoaded
+	*proc = &apiProc{call: call}
+	apiLoaded = true
+	t.Cleanup(func() {
+		*proc = oldProc
+		apiLoaded = oldLoaded
+	})
+}
+
+// returns the value a pointer argument passed to a driver procedure points to, as the driver sees it
+//
+//go:nocheckptr
+func argPtr[T any](arg uintptr) *T {
+	return (*T)(unsafe.Add(nil, arg))
+}
+
+func TestAPIReadPassesPointers(t *testing.T) {
+	var gotHandle TPCANHandle
+	stubProc(t, &pHandleRead, func(a ...uintptr) (uintptr, uintptr, error) {
+		if len(a) != 3 {
+			t.Fatalf("CAN_Read called with %v arguments, want 3", len(a))
+		}
+		gotHandle = TPCANHandle(a[0])
+		*argPtr[TPCANMsg](a[1]) = TPCANMsg{ID: 0x123, MsgType: PCAN_MESSAGE_STANDARD, DLC: 2, Data: [8]byte{0xAB, 0xCD}}
+		*argPtr[TPCANTimestamp](a[2]) = TPCANTimestamp{Millis: 42, MillisOverflow: 1, Micros: 7}
+		return uintptr(PCAN_ERROR_OK), 0, nil
+	})
+
+	status, msg, timestamp, err := APIRead(PCAN_USBBUS1)
+	if status != PCAN_ERROR_OK || err != nil {
+		t.Fatalf("APIRead() = %v, %v, want PCAN_ERROR_OK, nil", status, err)
+	}
+	if gotHandle != PCAN_USBBUS1 {
+		t.Errorf("handle = %#x, want %#x", gotHandle, PCAN_USBBUS1)
+	}
+	if msg.ID != 0x123 || msg.DLC != 2 || msg.Data[0] != 0xAB || msg.Data[1] != 0xCD {
+		t.Errorf("msg = %+v, not the message written by the driver", msg)
+	}
+	if timestamp != (TPCANTimestamp{Millis: 42, MillisOverflow: 1, Micros: 7}) {
+		t.Errorf("timestamp = %+v, not the timestamp written by the driver", timestamp)
+	}
+}
+
+func TestAPIReadNotLoaded(t *testing.T) {
+	if apiLoaded {
+		t.Skip("api is loaded")
+	}
+	status, _, _, err := APIRead(PCAN_USBBUS1)
+	if status != PCAN_ERROR_ILLOPERATION || !errors.Is(err, ErrAPINotLoaded) {
+		t.Errorf("APIRead() = %v, %v, want PCAN_ERROR_ILLOPERATION, ErrAPINotLoaded", status, err)
+	}
+}
+
+func TestAPIFunctionNotAvailable(t *testing.T) {
+	stubProc(t, &pHandleRead, nil)
+	oldProc := pHandleReadFD
+	pHandleReadFD = nil
+	t.Cleanup(func() { pHandleReadFD = oldProc })
+
+	status, _, _, err := APIReadFD(PCAN_USBBUS1)
+	if status != PCAN_ERROR_ILLOPERATION {
+		t.Errorf("status = %v, want PCAN_ERROR_ILLOPERATION", status)
+	}
+	if !errors.Is(err, ErrFunctionNotAvailable) || !errors.Is(err, ErrNotSupported) {
+		t.Errorf("err = %v, want ErrFunctionNotAvailable and ErrNotSupported", err)
+	}
+}
+
+func TestUnloadAPIWithoutLoad(t *testing.T) {
+	if driverOpen() {
+		t.Skip("driver is loaded")
+	}
+	for i := 0; i < 2; i++ {
+		if err := UnloadAPI(); err != nil {
+			t.Fatalf("UnloadAPI() %v = %v, want nil", i, err)
+		}
+	}
+	if apiLoaded || LoadedCapabilities().Loaded {
+		t.Error("api is reported as loaded after UnloadAPI()")
+	}
+	if status, _, _, err := APIRead(PCAN_USBBUS1); status != PCAN_ERROR_ILLOPERATION || !errors.Is(err, ErrAPINotLoaded) {
+		t.Errorf("APIRead() after UnloadAPI() = %v, %v, want ErrAPINotLoaded", status, err)
+	}
+}
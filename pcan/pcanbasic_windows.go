@@ -0,0 +1,174 @@
+//go:build windows
+
+package pcan
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+	"unicode/utf8"
+	"unsafe"
+)
+
+/* This file is the windows specific implementation for loading the PCAN driver and waiting for its events. */
+
+// handle of an event signaled by the driver
+type eventHandle = syscall.Handle
+
+var pcanAPIHandle *syscall.DLL = nil // procedure handle for PCAN driver
+
+// loads the driver library
+func openDriver() error {
+	var err error
+	pcanAPIHandle, err = syscall.LoadDLL("PCANBasic.dll")
+	if err != nil || pcanAPIHandle == nil {
+		pcanAPIHandle = nil
+		// windows refuses to map a driver of another architecture instead of failing on the first call
+		var errno syscall.Errno
+		if errors.As(err, &errno) && errno == errorBadExeFormat {
+			return fmt.Errorf("%w: %v", ErrArchitectureMismatch, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// returns a procedure of the loaded driver, nil if the driver does not export it
+func findDriverProc(name string) *apiProc {
+	proc, err := pcanAPIHandle.FindProc(name)
+	if err != nil || proc == nil {
+		return nil
+	}
+	return &apiProc{call: proc.Call}
+}
+
+// returns if the driver library is loaded
+func driverOpen() bool {
+	return pcanAPIHandle != nil
+}
+
+// releases the driver library
+func releaseDriver() error {
+	err := pcanAPIHandle.Release()
+	pcanAPIHandle = nil
+	return err
+}
+
+// creates the event signaled by the driver when a message is received
+// Note: The event is unnamed, not inherited and resets automatically when a waiting goroutine is released
+func createEvent() (eventHandle, error) {
+	procCreateEvent := syscall.NewLazyDLL("kernel32.dll").NewProc("CreateEventW")
+	if err := procCreateEvent.Find(); err != nil {
+		return 0, err
+	}
+	// CreateEventW(lpEventAttributes = NULL, bManualReset = FALSE, bInitialState = FALSE, lpName = NULL)
+	r0, _, errno := procCreateEvent.Call(0, 0, 0, 0)
+	if r0 == 0 {
+		return 0, errno
+	}
+	return syscall.Handle(r0), nil
+}
+
+// waits until the event is signaled
+// timeout: Maximum time to wait in milliseconds, infiniteTimeout waits without limit
+func waitEvent(event eventHandle, timeout uint32) (waitResult, error) {
+	val, err := syscall.WaitForSingleObject(event, timeout)
+	switch val {
+	case syscall.WAIT_OBJECT_0:
+		return waitSignaled, nil
+	case syscall.WAIT_TIMEOUT:
+		return waitTimeout, nil
+	default:
+		return waitFailed, err
+	}
+}
+
+// closes an event created by createEvent()
+func closeEvent(event eventHandle) error {
+	return syscall.CloseHandle(event)
+}
+
+// Registers an event owned by the caller which the driver signals when a message is received, e.g. to wait
+// for messages together with other handles in a WaitForMultipleObjects loop
+// event: Handle of an event created by the caller, zero unregisters the event
+// Note: Replaces and closes the event created by this package. The package never closes an event provided by
+// the caller: Shutdown() only unregisters it, a reinitialization of the channel registers it again.
+func (p *TPCANBus) SetReceiveEvent(event syscall.Handle) (TPCANStatus, error) {
+	p.connMutex.Lock()
+	defer p.connMutex.Unlock()
+	status, err := p.setRecvEventValue(event)
+	if status != PCAN_ERROR_OK || err != nil {
+		return status, err
+	}
+	_ = p.closeRecvEvent()
+	p.recvEvent = event
+	p.recvEventExternal = event != 0
+	return status, err
+}
+
+// helper function to encode a string as null terminated string in the systems ANSI code page as expected by the PCAN api
+// Note: Falls back to UTF-8 if the code page conversion is not available
+func encodeAnsiString(value string, buffer []byte) error {
+	const (
+		CP_ACP               = 0
+		WC_NO_BEST_FIT_CHARS = 0x400
+	)
+
+	if len(buffer) == 0 {
+		return errors.New("string buffer has a size of zero")
+	}
+	clear(buffer)
+
+	wide, err := syscall.UTF16FromString(value)
+	if err != nil {
+		return fmt.Errorf("invalid string %q: %v", value, err)
+	}
+
+	procConvert := syscall.NewLazyDLL("kernel32.dll").NewProc("WideCharToMultiByte")
+	if procConvert.Find() != nil {
+		if len(value)+1 > len(buffer) {
+			return fmt.Errorf("string %q exceeds max length of %v bytes including terminator", value, len(buffer))
+		}
+		copy(buffer, value)
+		return nil
+	}
+
+	var usedDefaultChar int32
+	r, _, errno := procConvert.Call(CP_ACP, WC_NO_BEST_FIT_CHARS, uintptr(unsafe.Pointer(&wide[0])), ^uintptr(0),
+		uintptr(unsafe.Pointer(&buffer[0])), uintptr(len(buffer)), 0, uintptr(unsafe.Pointer(&usedDefaultChar)))
+	if r == 0 {
+		if errno == syscall.ERROR_INSUFFICIENT_BUFFER {
+			return fmt.Errorf("string %q exceeds max length of %v bytes including terminator", value, len(buffer))
+		}
+		return fmt.Errorf("could not encode string %q: %v", value, errno)
+	}
+	if usedDefaultChar != 0 {
+		return fmt.Errorf("string %q contains characters not representable in the system code page", value)
+	}
+	return nil
+}
+
+// helper function to convert a null terminated string in the systems ANSI code page returned by the PCAN api to UTF-8
+// Note: Strings which are valid UTF-8 already (e.g. plain ASCII) are returned unchanged
+func decodeAnsiString(buffer []byte) string {
+	const CP_ACP = 0
+
+	text := cString(buffer)
+	if text == "" || utf8.ValidString(text) {
+		return text
+	}
+
+	procConvert := syscall.NewLazyDLL("kernel32.dll").NewProc("MultiByteToWideChar")
+	if procConvert.Find() != nil {
+		return strings.ToValidUTF8(text, "?")
+	}
+	wide := make([]uint16, len(text)+1)
+	raw := []byte(text)
+	r, _, _ := procConvert.Call(CP_ACP, 0, uintptr(unsafe.Pointer(&raw[0])), uintptr(len(raw)),
+		uintptr(unsafe.Pointer(&wide[0])), uintptr(len(wide)))
+	if r == 0 {
+		return strings.ToValidUTF8(text, "?")
+	}
+	return syscall.UTF16ToString(wide[:r])
+}
@@ -1,11 +1,15 @@
 package pcan
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
-	"syscall"
+	"slices"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 	"unsafe"
 )
 
@@ -14,24 +18,115 @@ import (
 // Initializes a basic plugNplay PCAN Channel
 // Channel: The handle of a PCAN Channel
 // baudRate: The speed for the communication (BTR0BTR1 code)
+// Note: Initializing an already initialized channel again returns the existing bus if the parameters match, otherwise ErrAlreadyInitialized
+// Note: Loads the api on first use, if RequireExplicitLoad is set ErrAPINotLoaded is returned instead
 func InitializeBasic(handle TPCANHandle, baudRate TPCANBaudrate) (TPCANStatus, *TPCANBus, error) {
-	LoadAPI()
+	status, bus, _, err := initializeBasic(handle, baudRate, nil)
+	return status, bus, err
+}
 
-	status, err := APIInitializeBasic(handle, baudRate)
-	if status != PCAN_ERROR_OK || err != nil {
-		return status, nil, err
+// initializes a basic plugNplay PCAN Channel, created is false if the bus of an already initialized channel is returned
+// preInit: Called for a new channel right before it is initialized, e.g. to configure the uninitialized handle, may be nil
+func initializeBasic(handle TPCANHandle, baudRate TPCANBaudrate, preInit func() (TPCANStatus, error)) (status TPCANStatus, bus *TPCANBus, created bool, err error) {
+	if err := loadAPIImplicitly(); err != nil {
+		return PCAN_ERROR_NODRIVER, nil, false, err
 	}
 
-	bus := TPCANBus{
+	initializedBusesMu.Lock()
+	defer initializedBusesMu.Unlock()
+
+	bus = &TPCANBus{
 		Handle:    handle,
 		Baudrate:  baudRate,
 		HWType:    PCAN_DEFAULT_HW_TYPE,
 		IOPort:    PCAN_DEFAULT_IO_PORT,
 		Interrupt: PCAN_DEFAULT_INTERRUPT}
+	if existing, found := initializedBuses[handle]; found {
+		status, bus, err = existingBus(existing, bus)
+		return status, bus, false, err
+	}
+	if _, found := initializedFDBuses[handle]; found {
+		return PCAN_ERROR_ILLOPERATION, nil, false, fmt.Errorf("%w: handle 0x%X is used as FD channel", ErrAlreadyInitialized, handle)
+	}
+
+	if preInit != nil {
+		if status, err = preInit(); status != PCAN_ERROR_OK || err != nil {
+			return status, nil, false, err
+		}
+	}
+	status, err = APIInitializeBasic(handle, baudRate)
+	if status != PCAN_ERROR_OK || err != nil {
+		return status, nil, false, err
+	}
 
 	bus.initializeRecvEvent()
+	initializedBuses[handle] = bus
 
-	return status, &bus, err
+	return status, bus, true, err
+}
+
+// Initializes a basic plugNplay PCAN Channel and retries if the channel is temporarily not available
+// handle: The handle of a PCAN Channel
+// baudRate: The speed for the communication (BTR0BTR1 code)
+// attempts: Maximum amount of initialization attempts
+// backoff: Wait time before the first retry, doubled with every further retry
+// Note: Only retries if the channel is still in use or not yet released, other errors fail immediately
+func InitializeBasicWithRetry(handle TPCANHandle, baudRate TPCANBaudrate, attempts int, backoff time.Duration) (TPCANStatus, *TPCANBus, error) {
+	var bus *TPCANBus
+	status, err := WithRetry(func() (TPCANStatus, error) {
+		status, initialized, err := InitializeBasic(handle, baudRate)
+		bus = initialized
+		return status, err
+	}, RetryPolicy{Attempts: attempts, Backoff: backoff, Retryable: isTransientInitStatus})
+	return status, bus, err
+}
+
+// checks if an initialization status indicates a channel which is only temporarily not available
+func isTransientInitStatus(status TPCANStatus) bool {
+	return status == PCAN_ERROR_INITIALIZE || status == PCAN_ERROR_HWINUSE || status == PCAN_ERROR_NETINUSE
+}
+
+// Initializes a basic plugNplay PCAN Channel and applies the given options before any message is read
+// handle: The handle of a PCAN Channel
+// baudRate: The speed for the communication (BTR0BTR1 code)
+// opts: Options applied right after initialization
+// Note: If an option can not be applied, the channel is uninitialized again and the failing status is returned
+// Note: Fails with ErrAlreadyInitialized if the channel is initialized already, its bus may be in use and is neither
+// reconfigured, reset nor uninitialized. Options of an existing bus are changed with its setters, e.g. SetListenOnly().
+func InitializeBasicWithOptions(handle TPCANHandle, baudRate TPCANBaudrate, opts InitOptions) (TPCANStatus, *TPCANBus, error) {
+	status, bus, created, err := initializeBasic(handle, baudRate, nil)
+	if status != PCAN_ERROR_OK || err != nil {
+		return status, nil, err
+	}
+	if !created {
+		return PCAN_ERROR_ILLOPERATION, nil, fmt.Errorf("%w: handle 0x%X, options are only applied to a new channel", ErrAlreadyInitialized, handle)
+	}
+
+	setters := []func() (TPCANStatus, error){
+		func() (TPCANStatus, error) { return bus.SetListenOnly(opts.ListenOnly) },
+		func() (TPCANStatus, error) { return bus.SetAllowStatusFrames(opts.AllowStatusFrames) },
+		func() (TPCANStatus, error) { return bus.SetAllowRTRFrames(opts.AllowRTRFrames) },
+		func() (TPCANStatus, error) { return bus.SetAllowErrorFrames(opts.AllowErrorFrames) },
+		func() (TPCANStatus, error) { return bus.SetAllowEchoFrames(opts.AllowEchoFrames) },
+	}
+	if opts.Power5V != nil {
+		setters = append(setters, func() (TPCANStatus, error) { return bus.SetPower5V(*opts.Power5V) })
+	}
+	for _, set := range setters {
+		status, err = set()
+		if status != PCAN_ERROR_OK || err != nil {
+			bus.Uninitialize()
+			return status, nil, err
+		}
+	}
+
+	// drop messages received before the configuration was complete
+	status, err = bus.Reset()
+	if status != PCAN_ERROR_OK || err != nil {
+		bus.Uninitialize()
+		return status, nil, err
+	}
+	return status, bus, err
 }
 
 // Initializes a advanced PCAN Channel
@@ -40,26 +135,49 @@ func InitializeBasic(handle TPCANHandle, baudRate TPCANBaudrate) (TPCANStatus, *
 // hwType: Non-PnP: The type of hardware and operation mode
 // ioPort: Non-PnP: The I/O address for the parallel port
 // interrupt: Non-PnP: Interrupt number of the parallel port
+// Note: Initializing an already initialized channel again returns the existing bus if the parameters match, otherwise ErrAlreadyInitialized
+// Note: Loads the api on first use, if RequireExplicitLoad is set ErrAPINotLoaded is returned instead
 func Initialize(handle TPCANHandle, baudRate TPCANBaudrate, hwType TPCANType, ioPort uint32, interrupt uint16) (TPCANStatus, *TPCANBus, error) {
-	LoadAPI()
-
-	status, err := APIInitialize(handle, baudRate, hwType, ioPort, interrupt)
-	if status != PCAN_ERROR_OK || err != nil {
-		return status, nil, err
+	if err := loadAPIImplicitly(); err != nil {
+		return PCAN_ERROR_NODRIVER, nil, err
 	}
 
+	initializedBusesMu.Lock()
+	defer initializedBusesMu.Unlock()
+
 	bus := TPCANBus{
 		Handle:    handle,
 		Baudrate:  baudRate,
 		HWType:    hwType,
 		IOPort:    ioPort,
 		Interrupt: interrupt}
+	if existing, found := initializedBuses[handle]; found {
+		return existingBus(existing, &bus)
+	}
+	if _, found := initializedFDBuses[handle]; found {
+		return PCAN_ERROR_ILLOPERATION, nil, fmt.Errorf("%w: handle 0x%X is used as FD channel", ErrAlreadyInitialized, handle)
+	}
+
+	status, err := APIInitialize(handle, baudRate, hwType, ioPort, interrupt)
+	if status != PCAN_ERROR_OK || err != nil {
+		return status, nil, err
+	}
 
 	bus.initializeRecvEvent()
+	initializedBuses[handle] = &bus
 
 	return status, &bus, err
 }
 
+// returns the bus of an already initialized channel if it was initialized with the requested parameters
+func existingBus(existing *TPCANBus, requested *TPCANBus) (TPCANStatus, *TPCANBus, error) {
+	if existing.Baudrate != requested.Baudrate || existing.HWType != requested.HWType ||
+		existing.IOPort != requested.IOPort || existing.Interrupt != requested.Interrupt {
+		return PCAN_ERROR_ILLOPERATION, nil, fmt.Errorf("%w: handle 0x%X", ErrAlreadyInitialized, existing.Handle)
+	}
+	return PCAN_ERROR_OK, existing, nil
+}
+
 // Initializes a FD capable PCAN Channel
 // handle: The handle of a PCAN Channel
 // bitRateFD: The speed for the communication (FD bit rate string)
@@ -72,26 +190,246 @@ func Initialize(handle TPCANHandle, baudRate TPCANBaudrate, hwType TPCANType, io
 //   - Following Parameters are optional (not used yet): data_ssp_offset, nom_sam
 //   - Example: f_clock=80000000,nom_brp=10,nom_tseg1=5,nom_tseg2=2,nom_sjw=1,data_brp=4,data_tseg1=7,data_tseg2=2,data_sjw=1
 func InitializeFD(handle TPCANHandle, bitRateFD TPCANBitrateFD) (TPCANStatus, *TPCANBusFD, error) {
-	LoadAPI()
+	return InitializeFDWithOptions(handle, bitRateFD, FDInitOptions{})
+}
+
+// Initializes a FD capable PCAN Channel from plain bit rates instead of a bit rate string
+// handle: The handle of a PCAN Channel
+// nominalBitrate: Bit rate of the arbitration phase in bit/s, e.g. 500000
+// dataBitrate: Bit rate of the data phase in bit/s, e.g. 2000000
+// clockMHz: Clock frequency of the CAN controller in MHz, e.g. 80
+// Note: The supported combinations are described at BuildBitrateFD(), a non-OK status is returned as *StatusError
+func InitializeFDSimple(handle TPCANHandle, nominalBitrate uint32, dataBitrate uint32, clockMHz uint32) (*TPCANBusFD, error) {
+	bitRateFD, err := BuildBitrateFD(clockMHz, nominalBitrate, dataBitrate)
+	if err != nil {
+		return nil, err
+	}
+	status, bus, err := InitializeFD(handle, bitRateFD)
+	if err != nil {
+		return nil, err
+	}
+	if status != PCAN_ERROR_OK {
+		return nil, &StatusError{Status: status}
+	}
+	return bus, nil
+}
+
+// Initializes a FD capable PCAN Channel and applies options which must be set before the initialization
+// handle: The handle of a PCAN Channel
+// bitRateFD: The speed for the communication (FD bit rate string), see InitializeFD()
+// opts: Options applied in the order required by the driver
+// Note: Bit rate adapting is enabled before CAN_InitializeFD, setting it on an initialized channel has no effect
+// Note: Initializing an already initialized channel again returns the existing bus if the bit rate matches, otherwise ErrAlreadyInitialized
+func InitializeFDWithOptions(handle TPCANHandle, bitRateFD TPCANBitrateFD, opts FDInitOptions) (TPCANStatus, *TPCANBusFD, error) {
+	if err := opts.Validate(); err != nil {
+		return PCAN_ERROR_ILLPARAMVAL, nil, err
+	}
+	if err := loadAPIImplicitly(); err != nil {
+		return PCAN_ERROR_NODRIVER, nil, err
+	}
+
+	initializedBusesMu.Lock()
+	defer initializedBusesMu.Unlock()
+
+	if _, found := initializedBuses[handle]; found {
+		return PCAN_ERROR_ILLOPERATION, nil, fmt.Errorf("%w: handle 0x%X is used as classic channel", ErrAlreadyInitialized, handle)
+	}
+	if existing, found := initializedFDBuses[handle]; found {
+		if existing.BitrateFD != bitRateFD {
+			return PCAN_ERROR_ILLOPERATION, nil, fmt.Errorf("%w: handle 0x%X", ErrAlreadyInitialized, handle)
+		}
+		return PCAN_ERROR_OK, existing, nil
+	}
+
+	if opts.BitrateAdapting {
+		status, err := SetBitrateAdapting(handle, true)
+		if status != PCAN_ERROR_OK || err != nil {
+			return status, nil, err
+		}
+	}
 
 	status, err := APIInitializeFD(handle, bitRateFD)
 	if status != PCAN_ERROR_OK || err != nil {
 		return status, nil, err
 	}
 
-	return PCAN_ERROR_UNKNOWN, nil, errors.New("not implemented") // TODO
+	bus := &TPCANBusFD{Handle: handle, BitrateFD: bitRateFD}
+	initializedFDBuses[handle] = bus
+	return status, bus, err
+}
+
+// Uninitializes a FD capable PCAN Channel
+func (p *TPCANBusFD) Uninitialize() (TPCANStatus, error) {
+	initializedBusesMu.Lock()
+	if initializedFDBuses[p.Handle] == p {
+		delete(initializedFDBuses, p.Handle)
+	}
+	initializedBusesMu.Unlock()
+
+	return APIUninitialize(p.Handle)
+}
+
+// Returns the handles of all channels initialized by this package and not uninitialized yet, in ascending order
+// Note: A handle is owned by a single bus, initializing it again returns that bus or fails with ErrAlreadyInitialized
+func ActiveHandles() []TPCANHandle {
+	initializedBusesMu.Lock()
+	defer initializedBusesMu.Unlock()
+
+	handles := make([]TPCANHandle, 0, len(initializedBuses)+len(initializedFDBuses))
+	for handle := range initializedBuses {
+		handles = append(handles, handle)
+	}
+	for handle := range initializedFDBuses {
+		handles = append(handles, handle)
+	}
+	slices.Sort(handles)
+	return handles
 }
 
 // Uninitializes PCAN Channels initialized by CAN_Initialize
+// Note: The receive event created by this package is closed, an event set by SetReceiveEvent() is only forgotten
 func (p *TPCANBus) Uninitialize() (TPCANStatus, error) {
+	p.connMutex.Lock()
+	defer p.connMutex.Unlock()
+	status, err := p.uninitialize()
+	_ = p.closeRecvEvent()
+	return status, err
+}
+
+// uninitializes the channel and keeps its receive event for initializing it again, the connection mutex must be held
+func (p *TPCANBus) uninitialize() (TPCANStatus, error) {
+	initializedBusesMu.Lock()
+	if initializedBuses[p.Handle] == p {
+		delete(initializedBuses, p.Handle)
+	}
+	initializedBusesMu.Unlock()
+
 	return APIUninitialize(p.Handle)
 }
 
 // Resets the receive and transmit queues of the PCAN Channel
+// Note: This does not restart the CAN controller, use HardReset() to recover from a wedged controller
 func (p *TPCANBus) Reset() (TPCANStatus, error) {
 	return APIReset(p.Handle)
 }
 
+// Enables or disables the automatic reset of the CAN controller by the driver after a bus-off condition
+// on: Bus-off recovery is done by the driver if set to true
+func (p *TPCANBus) SetAutoReset(on bool) (TPCANStatus, error) {
+	var conv = map[bool]TPCANParameterValue{false: PCAN_PARAMETER_OFF, true: PCAN_PARAMETER_ON}
+	return p.SetParameter(PCAN_BUSOFF_AUTORESET, conv[on])
+}
+
+// Restarts the CAN controller of the PCAN Channel in addition to resetting its queues
+// Note: If the driver supports hard resets (PCAN_HARD_RESET_STATUS), the reset is done with a single CAN_Reset call.
+// Otherwise the channel is uninitialized and initialized again with the parameters it was created with.
+func (p *TPCANBus) HardReset() (TPCANStatus, error) {
+	status, err := p.SetParameter(PCAN_HARD_RESET_STATUS, PCAN_PARAMETER_ON)
+	if status == PCAN_ERROR_OK && err == nil {
+		status, err = p.Reset()
+		_, _ = p.SetParameter(PCAN_HARD_RESET_STATUS, PCAN_PARAMETER_OFF)
+		return status, err
+	}
+	if status != PCAN_ERROR_ILLPARAMTYPE {
+		return status, err
+	}
+
+	// fallback for drivers without hard reset support
+	p.connMutex.Lock()
+	defer p.connMutex.Unlock()
+	status, err = p.uninitialize()
+	if status != PCAN_ERROR_OK || err != nil {
+		return status, err
+	}
+	return p.reinitialize()
+}
+
+// Initializes the PCAN Channel again with the parameters it was created with, e.g. after the device was replugged
+// Note: Errors while uninitializing are ignored as the channel may already be gone together with its device
+// Note: Safe to call while another goroutine reads, the read waits until the channel is initialized again
+func (p *TPCANBus) Reconnect() (TPCANStatus, error) {
+	p.connMutex.Lock()
+	defer p.connMutex.Unlock()
+	_, _ = p.uninitialize()
+	return p.reinitialize()
+}
+
+// initializes the uninitialized channel with its stored parameters and registers the receive event again
+// Note: The existing receive event is kept, so a goroutine waiting for it is not left with a closed handle.
+// The connection mutex must be held.
+func (p *TPCANBus) reinitialize() (TPCANStatus, error) {
+	status, err := APIInitialize(p.Handle, p.Baudrate, p.HWType, p.IOPort, p.Interrupt)
+	if status != PCAN_ERROR_OK || err != nil {
+		return status, err
+	}
+	if p.recvEvent != 0 {
+		status, err = p.setRecvEventValue(p.recvEvent)
+		if status != PCAN_ERROR_OK || err != nil {
+			return status, err
+		}
+	} else {
+		p.initializeRecvEvent()
+	}
+	p.isShutdown.Store(false)
+
+	initializedBusesMu.Lock()
+	initializedBuses[p.Handle] = p
+	initializedBusesMu.Unlock()
+	return status, err
+}
+
+// Shuts the PCAN Channel down in an orderly way: stops a running trace, drains the receive queue, closes the receive event and uninitializes the channel
+// drain: Called for every message left in the receive queue, nil discards the remaining messages
+// Note: All steps are executed even if one fails, the errors of all steps are returned together
+// Note: Draining stops after SHUTDOWN_DRAIN_DURATION or SHUTDOWN_DRAIN_LIMIT messages, so a busy bus can not delay
+// the shutdown, messages still received afterwards are discarded
+// Note: Calling Shutdown again on a channel already shut down or while it is shut down does nothing
+func (p *TPCANBus) Shutdown(drain func(*TPCANMsg)) error {
+	if !p.isShutdown.CompareAndSwap(false, true) {
+		return nil
+	}
+
+	var errs []error
+	statusErr := func(step string, status TPCANStatus, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", step, err))
+		} else if status != PCAN_ERROR_OK {
+			errs = append(errs, fmt.Errorf("%v failed with status 0x%X", step, status))
+		}
+	}
+
+	p.stopTracePruning()
+	status, tracing, err := p.TraceStatus()
+	if tracing {
+		status, err = p.StopTrace()
+	}
+	statusErr("stopping trace", status, err)
+
+	if drain != nil {
+		msgs, _, err := p.ReadFor(SHUTDOWN_DRAIN_DURATION, SHUTDOWN_DRAIN_LIMIT)
+		for i := range msgs {
+			drain(&msgs[i])
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("draining receive queue: %w", err))
+		}
+	}
+
+	p.connMutex.Lock()
+	if p.recvEvent != 0 {
+		status, err = p.setRecvEventValue(0)
+		statusErr("unregistering receive event", status, err)
+		if err = p.closeRecvEvent(); err != nil {
+			errs = append(errs, fmt.Errorf("closing receive event: %w", err))
+		}
+	}
+	p.connMutex.Unlock()
+
+	status, err = p.Uninitialize()
+	statusErr("uninitializing", status, err)
+	return errors.Join(errs...)
+}
+
 // Gets the current status of a PCAN Channel
 func (p *TPCANBus) GetStatus() (TPCANStatus, error) {
 	return APIGetStatus(p.Handle)
@@ -99,66 +437,203 @@ func (p *TPCANBus) GetStatus() (TPCANStatus, error) {
 
 // Reads a CAN message from the receive queue of a PCAN Channel
 // Note: Does return nil if receive buffer is empty
+// Note: Messages dropped by the software allowlist or filter (see SetSoftwareIDAllowlist, SetSoftwareFilter) are skipped
+// Note: A DLC above 8 is clamped to 8 so Data[:DLC] is always valid, see DLCAnomalies()
+// Note: The identifier is masked to 11 bits for standard and 29 bits for extended frames, stray high bits are dropped
+// Note: Lost messages are reported by the overflow bits of the status, see IsRxOverflow() and RxOverflows()
+// Note: The returned message and timestamp are new copies owned by the caller, later reads never reuse or modify them
+// Note: Messages read by WriteConfirmed() while it waited for an echo are returned first
 func (p *TPCANBus) Read() (TPCANStatus, *TPCANMsg, *TPCANTimestamp, error) {
-	status, msg, timestamp, err := APIRead(p.Handle)
-	if status == PCAN_ERROR_QRCVEMPTY {
-		return status, nil, nil, err
-	} else {
+	if frame, ok := p.popUnread(); ok {
+		return frame.status, &frame.msg, &frame.timestamp, nil
+	}
+	return p.read()
+}
+
+// reads the next message from the receive queue of the driver, see Read()
+func (p *TPCANBus) read() (TPCANStatus, *TPCANMsg, *TPCANTimestamp, error) {
+	for {
+		p.connMutex.RLock()
+		status, msg, timestamp, err := apiRead(p.Handle)
+		p.connMutex.RUnlock()
+		if status == PCAN_ERROR_QRCVEMPTY {
+			return status, nil, nil, err
+		}
+		if IsRxOverflow(status) {
+			p.rxOverflows.Add(1)
+		}
+		msg.ID = normalizeID(msg.ID, msg.MsgType)
+		if status == PCAN_ERROR_OK && err == nil && !p.isAllowed(&msg) {
+			continue
+		}
+		if msg.DLC > LENGTH_DATA_CAN_MESSAGE {
+			msg.DLC = LENGTH_DATA_CAN_MESSAGE
+			p.dlcClamped.Add(1)
+		}
+		if status == PCAN_ERROR_OK && msg.MsgType&(PCAN_MESSAGE_STATUS|PCAN_MESSAGE_ERRFRAME) == 0 {
+			now := time.Now()
+			p.updateTimeReference(&timestamp, now)
+			if cache := p.snapshot.Load(); cache != nil {
+				cache.Update(&msg, now)
+			}
+			if msg.MsgType&PCAN_MESSAGE_ECHO != 0 {
+				p.confirmEcho(&msg)
+			}
+		}
 		return status, &msg, &timestamp, err
 	}
 }
 
+// Returns the amount of reads which reported lost messages due to a receive overflow
+func (p *TPCANBus) RxOverflows() uint64 {
+	return p.rxOverflows.Load()
+}
+
+// Returns if a status reports lost messages because the receive queue or the CAN controller was read too late
+func IsRxOverflow(status TPCANStatus) bool {
+	return status&(PCAN_ERROR_QOVERRUN|PCAN_ERROR_OVERRUN) != 0
+}
+
+// Returns the amount of received messages with an invalid DLC above 8 which were clamped by Read()
+func (p *TPCANBus) DLCAnomalies() uint64 {
+	return p.dlcClamped.Load()
+}
+
 // Reads a CAN message from the receive queue of a PCAN Channel with an timeout and only returns a valid messsage
 // Note: Does return nil if receive buffer is empty or no message is read during timeout
 // timeout: Timeout for receiving message from CAN bus in milliseconds (if set below zero, no timeout is set)
+// Note: Waits with the strategy reported by ReadStrategy(), either for the receive event or by polling the queue
+// Note: When polling, the queue is read a last time at the deadline and the time is taken from Clock()
 func (p *TPCANBus) ReadWithTimeout(timeout int) (TPCANStatus, *TPCANMsg, *TPCANTimestamp, error) {
+	clock := p.Clock()
+	endTime := clock.Now().Add(time.Duration(timeout) * time.Millisecond)
 
-	var ret = PCAN_ERROR_UNKNOWN
-	var msg *TPCANMsg = nil
-	var timestamp *TPCANTimestamp = nil
-	var err error = nil
+	for {
+		ret, msg, timestamp, err := p.Read()
+		if ret != PCAN_ERROR_QRCVEMPTY || msg != nil {
+			return ret, msg, timestamp, err
+		}
 
-	// timeout handling: a negative timeout sets timeout to infinity
-	if timeout < 0 {
-		timeout = syscall.INFINITE
+		// timeout handling: a negative timeout waits without limit, the read at the deadline is the last one
+		remaining := endTime.Sub(clock.Now())
+		if timeout >= 0 && remaining <= 0 {
+			return ret, nil, nil, err
+		}
+
+		switch p.ReadStrategy() {
+		case ReadStrategyEvent:
+			wait := infiniteTimeout
+			if timeout >= 0 {
+				wait = uint32(max(remaining.Milliseconds(), 1))
+			}
+			val, errWait := waitForEvent(p.receiveEvent(), wait)
+			if val != waitSignaled {
+				return ret, nil, nil, errWait
+			}
+		default:
+			sleep := p.PollInterval()
+			if timeout >= 0 {
+				sleep = min(sleep, remaining)
+			}
+			clock.Sleep(sleep)
+		}
 	}
-	var timeoutU32 = uint32(timeout)
-	startTime := time.Now().UnixNano() / int64(time.Millisecond)
-	endTime := startTime + int64(timeout)
+}
 
-	// receive message
-	for msg == nil {
-		ret, msg, timestamp, err = p.Read()
-		if ret == PCAN_ERROR_QRCVEMPTY {
-			if hasEvents {
-				val, errWait := syscall.WaitForSingleObject(p.recvEvent, timeoutU32)
-				switch val {
-				case syscall.WAIT_OBJECT_0:
-					break
-				case syscall.WAIT_FAILED:
-					return ret, nil, nil, errWait
-				case syscall.WAIT_TIMEOUT:
-					return ret, nil, nil, errWait
-				default:
-					return ret, msg, timestamp, errWait
-				}
-			} else {
-				// timeout handling
-				if time.Now().UnixNano()/int64(time.Millisecond) > endTime {
-					return ret, nil, nil, err
-				}
-				time.Sleep(250 * time.Microsecond)
+// Waits until a message is received or the channel reports an error, only an empty receive queue keeps waiting
+// timeout: Maximum time to wait, below zero waits without limit
+// Note: Any status other than PCAN_ERROR_OK and PCAN_ERROR_QRCVEMPTY is returned immediately together with a
+// *StatusError. The bus status is checked while the queue is empty, so e.g. a bus-off is reported without a
+// message being received. A message read together with an overflow status is returned with the error.
+// Note: Returns PCAN_ERROR_QRCVEMPTY and no error if the timeout elapsed
+func (p *TPCANBus) ReadOrError(timeout time.Duration) (TPCANStatus, *TPCANMsg, *TPCANTimestamp, error) {
+	clock := p.Clock()
+	deadline := clock.Now().Add(timeout)
+
+	for {
+		status, msg, timestamp, err := p.Read()
+		if err != nil {
+			return status, nil, nil, err
+		}
+		switch {
+		case status == PCAN_ERROR_OK:
+			return status, msg, timestamp, nil
+		case status&^(PCAN_ERROR_QOVERRUN|PCAN_ERROR_OVERRUN) == 0:
+			return status, msg, timestamp, &StatusError{Status: status}
+		case status != PCAN_ERROR_QRCVEMPTY:
+			return status, nil, nil, &StatusError{Status: status}
+		}
+
+		busStatus, err := p.GetStatus()
+		if err != nil {
+			return busStatus, nil, nil, err
+		}
+		if busStatus&PCAN_ERROR_ANYBUSERR != 0 {
+			return busStatus, nil, nil, &StatusError{Status: busStatus}
+		}
+
+		// wait for at most one poll interval, so the bus status is checked again even without messages
+		wait := p.PollInterval()
+		if timeout >= 0 {
+			remaining := deadline.Sub(clock.Now())
+			if remaining <= 0 {
+				return PCAN_ERROR_QRCVEMPTY, nil, nil, nil
+			}
+			wait = min(wait, remaining)
+		}
+		if p.ReadStrategy() == ReadStrategyEvent {
+			if val, errWait := waitForEvent(p.receiveEvent(), uint32(max(wait.Milliseconds(), 1))); val == waitFailed {
+				return status, nil, nil, errWait
 			}
+		} else {
+			clock.Sleep(wait)
 		}
 	}
+}
 
-	return ret, msg, timestamp, err
+// Returns the strategy used by ReadWithTimeout() to wait for messages
+// Note: The PCAN-Basic driver offers no blocking read, so the receive event is used if UseReceiveEvents was set when
+// the channel was initialized and the event could be created, the receive queue is polled otherwise
+func (p *TPCANBus) ReadStrategy() ReadStrategy {
+	if p.receiveEvent() != 0 {
+		return ReadStrategyEvent
+	}
+	return ReadStrategyPolling
+}
 
+// returns the current receive event, zero if there is none
+func (p *TPCANBus) receiveEvent() eventHandle {
+	p.connMutex.RLock()
+	defer p.connMutex.RUnlock()
+	return p.recvEvent
+}
+
+// Returns the sleep between two reads if the receive queue is polled
+func (p *TPCANBus) PollInterval() time.Duration {
+	if p.pollInterval <= 0 {
+		return DEFAULT_POLL_INTERVAL
+	}
+	return p.pollInterval
+}
+
+// Configures the sleep between two reads if the receive queue is polled
+// interval: Sleep between two reads, zero or below restores DEFAULT_POLL_INTERVAL
+func (p *TPCANBus) SetPollInterval(interval time.Duration) {
+	p.pollInterval = interval
 }
 
 // Reads from device buffer until it has no more messages stored with an optional message limit
 // If limit is set to zero, no limit will will be used
+// Note: If reading fails, the messages read so far are returned together with the error
 func (p *TPCANBus) ReadFullBuffer(limit int) ([]TPCANMsg, []TPCANTimestamp, error) {
+	return p.ReadFor(0, limit)
+}
+
+// Reads from device buffer until it has no more messages stored, the message limit is reached or the duration elapsed
+// duration: Time budget for reading, if set to zero or below no time bound will be used
+// limit: Maximum amount of messages, if set to zero no limit will be used
+// Note: If reading fails, the messages read so far are returned together with the error
+func (p *TPCANBus) ReadFor(duration time.Duration, limit int) ([]TPCANMsg, []TPCANTimestamp, error) {
 
 	var ret = PCAN_ERROR_UNKNOWN
 	var msg *TPCANMsg = nil
@@ -166,30 +641,111 @@ func (p *TPCANBus) ReadFullBuffer(limit int) ([]TPCANMsg, []TPCANTimestamp, erro
 	var msgs []TPCANMsg
 	var timestamps []TPCANTimestamp
 	var err error = nil
+	var deadline time.Time
 
-	// read until buffer empty is returned
+	if duration > 0 {
+		deadline = time.Now().Add(duration)
+	}
+
+	// read until buffer empty is returned, the limit is reached or the time is up
 	for {
 		ret, msg, timestamp, err = p.Read()
 		if ret == PCAN_ERROR_QRCVEMPTY {
 			return msgs, timestamps, err
+		} else if err != nil {
+			return msgs, timestamps, err
+		} else if (ret != PCAN_ERROR_OK && !IsRxOverflow(ret)) || msg == nil || timestamp == nil {
+			return msgs, timestamps, fmt.Errorf("reading receive queue failed with status 0x%X", ret)
 		} else {
 			msgs = append(msgs, *msg)
 			timestamps = append(timestamps, *timestamp)
 			if limit != 0 && len(msgs) >= int(limit) {
 				return msgs, timestamps, err
 			}
+			if !deadline.IsZero() && !time.Now().Before(deadline) {
+				return msgs, timestamps, err
+			}
+		}
+	}
+}
+
+// Waits until at least minCount messages were received or the timeout elapsed and returns all messages read
+// minCount: Amount of messages to wait for, all messages available when it is reached are returned as well
+// timeout: Maximum time to wait for minCount messages
+// Note: On timeout the messages received so far are returned without an error
+// Note: If reading fails, the messages read so far are returned together with the error
+func (p *TPCANBus) ReadBatch(minCount int, timeout time.Duration) ([]TPCANMsg, []TPCANTimestamp, error) {
+	var msgs []TPCANMsg
+	var timestamps []TPCANTimestamp
+	deadline := time.Now().Add(timeout)
+
+	for {
+		batch, batchTimestamps, err := p.ReadFullBuffer(0)
+		msgs = append(msgs, batch...)
+		timestamps = append(timestamps, batchTimestamps...)
+		if err != nil || len(msgs) >= minCount {
+			return msgs, timestamps, err
+		}
+
+		// wait for the next message instead of polling the empty queue
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return msgs, timestamps, nil
+		}
+		_, msg, timestamp, err := p.ReadWithTimeout(int(max(remaining.Milliseconds(), 1)))
+		if err != nil {
+			return msgs, timestamps, err
+		}
+		if msg != nil && timestamp != nil {
+			msgs = append(msgs, *msg)
+			timestamps = append(timestamps, *timestamp)
+		}
+	}
+}
+
+// Reads and discards messages until no message was received for the quiet period
+// quiet: Time without any received message after which the bus is considered idle
+// timeout: Maximum time to wait for the bus to become idle
+// Note: Returns an error if messages keep arriving until the timeout elapsed
+func (p *TPCANBus) WaitForIdle(quiet time.Duration, timeout time.Duration) error {
+	start := time.Now()
+	deadline := start.Add(timeout)
+	lastMessage := start
+
+	for {
+		now := time.Now()
+		idleAt := lastMessage.Add(quiet)
+		if !now.Before(idleAt) {
+			return nil
+		}
+		if !now.Before(deadline) {
+			return fmt.Errorf("bus did not become idle for %v within %v", quiet, timeout)
+		}
+
+		wait := min(idleAt.Sub(now), deadline.Sub(now))
+		_, msg, _, err := p.ReadWithTimeout(int(max(wait.Milliseconds(), 1)))
+		if err != nil {
+			return err
+		}
+		if msg != nil {
+			lastMessage = time.Now()
 		}
 	}
 }
 
 // Reads a CAN message from the receive queue of a FD capable PCAN Channel
+// Note: Returns PCAN_ERROR_ILLDATA if the DLC is no valid CAN FD DLC code
+// Note: The identifier is masked to 11 bits for standard and 29 bits for extended frames
 func (p *TPCANBusFD) ReadFD() (TPCANStatus, *TPCANMsgFD, *TPCANTimestampFD, error) {
-	status, msg, timestamp, err := APIReadFD(p.Handle)
+	status, msg, timestamp, err := apiReadFD(p.Handle)
 	if status == PCAN_ERROR_QRCVEMPTY {
 		return status, nil, nil, err
-	} else {
-		return status, &msg, &timestamp, err
 	}
+	if int(msg.DLC) >= len(fdDLCLengths) {
+		return PCAN_ERROR_ILLDATA, nil, nil, fmt.Errorf("received message 0x%X with invalid CAN FD DLC of %v", msg.ID, msg.DLC)
+	}
+	msg.ID = normalizeID(msg.ID, msg.MsgType)
+	return status, &msg, &timestamp, err
 }
 
 // Transmits a CAN message
@@ -198,29 +754,301 @@ func (p *TPCANBus) Write(msg *TPCANMsg) (TPCANStatus, error) {
 	return APIWrite(p.Handle, msg)
 }
 
+// Transmits a CAN message and retries while the transmit queue is full until ctx is done
+// msg: A Message struct with the message to be sent
+// Note: Returns ctx.Err() together with the last status if ctx is done before the message was queued
+func (p *TPCANBus) WriteContext(ctx context.Context, msg *TPCANMsg) (TPCANStatus, error) {
+	for {
+		status, err := p.Write(msg)
+		if err != nil || status&(PCAN_ERROR_QXMTFULL|PCAN_ERROR_XMTFULL) == 0 {
+			return status, err
+		}
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-time.After(p.PollInterval()):
+		}
+	}
+}
+
+// Transmits a CAN message and waits until its transmission is confirmed
+// msg: A Message struct with the message to be sent
+// timeout: Maximum time to wait for the confirmation
+// Note: With echo frames allowed (see SetAllowEchoFrames), the message is confirmed by its echo frame and the call
+// returns as soon as the echo is read. The receive queue is read while waiting, the messages read are returned again
+// by the next Read(), so no reader misses them. Returns an error if the echo is not received within the timeout.
+// Note: Only if echo frames are not allowed or not supported by the driver, the bus status is watched instead, as the
+// driver offers no other confirmation per message. A message which is not acknowledged by any other node is
+// retransmitted by the controller, raising its transmit error counter until GetStatus reports a bus error.
+// The message is considered transmitted if no bus error is reported within the timeout, so the call always
+// takes the full timeout on success. A few milliseconds are enough to detect missing acknowledges.
+func (p *TPCANBus) WriteConfirmed(msg *TPCANMsg, timeout time.Duration) (TPCANStatus, error) {
+	if p.echoFramesAllowed() {
+		return p.writeConfirmedByEcho(msg, timeout)
+	}
+
+	status, err := p.Write(msg)
+	if status != PCAN_ERROR_OK || err != nil {
+		return status, err
+	}
+
+	endTime := time.Now().Add(timeout)
+	for {
+		status, err = p.GetStatus()
+		if err != nil {
+			return status, err
+		}
+		if status&PCAN_ERROR_ANYBUSERR != 0 {
+			return status, fmt.Errorf("message 0x%X could not be transmitted, bus status 0x%X", msg.ID, status)
+		}
+		if time.Now().After(endTime) {
+			return PCAN_ERROR_OK, nil
+		}
+		time.Sleep(p.PollInterval())
+	}
+}
+
+// checks if echo frames of sent messages are received
+func (p *TPCANBus) echoFramesAllowed() bool {
+	status, value, err := p.GetParameter(PCAN_ALLOW_ECHO_FRAMES)
+	return status == PCAN_ERROR_OK && err == nil && value == PCAN_PARAMETER_ON
+}
+
+// transmits a CAN message and waits until its echo frame is read, by this or by another goroutine
+func (p *TPCANBus) writeConfirmedByEcho(msg *TPCANMsg, timeout time.Duration) (TPCANStatus, error) {
+	// registered before writing, as the echo may be read by another goroutine before Write returns
+	confirmed := make(chan struct{})
+	p.addPendingEcho(pendingEcho{msg: *msg, confirmed: confirmed})
+	defer p.removeConfirmation(confirmed)
+
+	status, err := p.Write(msg)
+	if status != PCAN_ERROR_OK || err != nil {
+		return status, err
+	}
+
+	endTime := time.Now().Add(timeout)
+	for {
+		select {
+		case <-confirmed:
+			return PCAN_ERROR_OK, nil
+		default:
+		}
+
+		status, received, timestamp, err := p.read()
+		if err != nil {
+			return status, err
+		}
+		if received != nil {
+			p.pushUnread(unreadFrame{status: status, msg: *received, timestamp: *timestamp})
+			continue
+		}
+
+		status, err = p.GetStatus()
+		if err != nil {
+			return status, err
+		}
+		if status&PCAN_ERROR_ANYBUSERR != 0 {
+			return status, fmt.Errorf("message 0x%X could not be transmitted, bus status 0x%X", msg.ID, status)
+		}
+		if time.Now().After(endTime) {
+			return PCAN_ERROR_QRCVEMPTY, fmt.Errorf("echo of message 0x%X was not received within %v", msg.ID, timeout)
+		}
+		time.Sleep(p.PollInterval())
+	}
+}
+
+// Transmits a CAN message built from an identifier and raw data
+// id: The 11/29-bit message identifier
+// extended: Sends an extended frame (29-bit identifier) if set to true
+// data: Data of the message, at most 8 bytes
+func (p *TPCANBus) WriteRaw(id TPCANMsgID, extended bool, data []byte) (TPCANStatus, error) {
+	if err := validateID(id, extended); err != nil {
+		return PCAN_ERROR_ILLPARAMVAL, err
+	}
+	if len(data) > LENGTH_DATA_CAN_MESSAGE {
+		return PCAN_ERROR_ILLPARAMVAL, fmt.Errorf("data length of %v exceeds maximum of %v bytes", len(data), LENGTH_DATA_CAN_MESSAGE)
+	}
+
+	msg := TPCANMsg{ID: id, MsgType: msgTypeFor(extended), DLC: uint8(len(data))}
+	copy(msg.Data[:], data)
+	return p.Write(&msg)
+}
+
+// Transmits a remote transmission request frame
+// id: The 11/29-bit message identifier
+// extended: Sends an extended frame (29-bit identifier) if set to true
+// dlc: Requested data length code (0..8)
+func (p *TPCANBus) WriteRTR(id TPCANMsgID, extended bool, dlc uint8) (TPCANStatus, error) {
+	if err := validateID(id, extended); err != nil {
+		return PCAN_ERROR_ILLPARAMVAL, err
+	}
+	if dlc > LENGTH_DATA_CAN_MESSAGE {
+		return PCAN_ERROR_ILLPARAMVAL, fmt.Errorf("dlc of %v exceeds maximum of %v", dlc, LENGTH_DATA_CAN_MESSAGE)
+	}
+
+	msg := TPCANMsg{ID: id, MsgType: msgTypeFor(extended) | PCAN_MESSAGE_RTR, DLC: dlc}
+	return p.Write(&msg)
+}
+
 // Transmits a CAN message over a FD capable PCAN Channel
 // msgFD A MessageFD struct with the message to be sent
 func (p *TPCANBusFD) WriteFD(msg *TPCANMsgFD) (TPCANStatus, error) {
 	return APIWriteFD(p.Handle, msg)
 }
 
+// Transmits a CAN FD message built from an identifier and raw data
+// id: The 11/29-bit message identifier
+// extended: Sends an extended frame (29-bit identifier) if set to true
+// brs: Sends the data with the higher data bit rate (bit rate switch) if set to true
+// data: Data of the message, length must be one of 0..8, 12, 16, 20, 24, 32, 48 or 64 bytes
+func (p *TPCANBusFD) WriteFDRaw(id TPCANMsgID, extended bool, brs bool, data []byte) (TPCANStatus, error) {
+	if err := validateID(id, extended); err != nil {
+		return PCAN_ERROR_ILLPARAMVAL, err
+	}
+	dlc, err := FDLengthToDLC(len(data))
+	if err != nil {
+		return PCAN_ERROR_ILLPARAMVAL, err
+	}
+
+	msg := TPCANMsgFD{ID: id, MsgType: msgTypeFor(extended) | PCAN_MESSAGE_FD, DLC: dlc}
+	if brs {
+		msg.MsgType |= PCAN_MESSAGE_BRS
+	}
+	copy(msg.Data[:], data)
+	return p.WriteFD(&msg)
+}
+
+// checks if a message identifier fits into the 11-bit or 29-bit identifier range
+func validateID(id TPCANMsgID, extended bool) error {
+	if _, ok := id.Extended(); extended && !ok {
+		return fmt.Errorf("id 0x%X exceeds maximum extended id 0x%X", id, MAX_EXTENDED_ID)
+	}
+	if _, ok := id.Standard(); !extended && !ok {
+		return fmt.Errorf("id 0x%X exceeds maximum standard id 0x%X", id, MAX_STANDARD_ID)
+	}
+	return nil
+}
+
+// returns the message type for a standard or extended frame
+func msgTypeFor(extended bool) TPCANMessageType {
+	if extended {
+		return PCAN_MESSAGE_EXTENDED
+	}
+	return PCAN_MESSAGE_STANDARD
+}
+
 // Configures the reception filter
 // fromID: The lowest CAN ID to be received
 // toID: The highest CAN ID to be received
 // mode: Message type, Standard (11-bit identifier) or Extended (29-bit identifier)
+// Note: Replaces a range set before, only messages within the range are received afterwards
 func (p *TPCANBus) SetFilter(fromID TPCANMsgID, toID TPCANMsgID, mode TPCANMode) (TPCANStatus, error) {
-	status, err := APISetFilter(p.Handle, fromID, toID, mode)
-	if status != PCAN_ERROR_OK {
+	// the driver widens an existing filter by the range, so it is closed first to only receive the range
+	status, err := p.SetParameter(PCAN_MESSAGE_FILTER, TPCANParameterValue(PCAN_FILTER_CLOSE))
+	if status != PCAN_ERROR_OK || err != nil {
 		return status, err
 	}
-	return p.SetParameter(PCAN_MESSAGE_FILTER, TPCANParameterValue(PCAN_FILTER_CLOSE)) // confirm filter
+	return APISetFilter(p.Handle, fromID, toID, mode)
 }
 
 // Resets message filter set by SetFilter() function
+// Note: This does not reopen an acceptance filter set by SetAcceptanceFilter(), use ResetAcceptanceFilter() for that
 func (p *TPCANBus) ResetFilter() (TPCANStatus, error) {
 	return p.SetParameter(PCAN_MESSAGE_FILTER, TPCANParameterValue(PCAN_FILTER_OPEN))
 }
 
+// Configures the acceptance filter of the CAN controller with a code and mask pattern
+// code: Identifier bits a message must have at the positions not masked
+// mask: Identifier bits which are not checked ("don't care" if set)
+// extended: Configures the filter for 29-bit identifiers (PCAN_ACCEPTANCE_FILTER_29BIT), otherwise for 11-bit identifiers
+// Note: The acceptance filter and the range filter of SetFilter() are independent, a message is only received if
+// it passes both. ResetFilter() only opens the range filter.
+func (p *TPCANBus) SetAcceptanceFilter(code TPCANMsgID, mask TPCANMsgID, extended bool) (TPCANStatus, error) {
+	param := PCAN_ACCEPTANCE_FILTER_11BIT
+	if extended {
+		param = PCAN_ACCEPTANCE_FILTER_29BIT
+	}
+	// the driver expects the code in the upper and the mask in the lower 32 bits
+	value := uint64(code)<<32 | uint64(mask)
+	return p.SetValue(param, unsafe.Pointer(&value), uint32(unsafe.Sizeof(value)))
+}
+
+// Opens the acceptance filters for 11-bit and 29-bit identifiers again, so every identifier passes
+// Note: The range filter of SetFilter() is not changed, use ResetFilter() to open it as well
+func (p *TPCANBus) ResetAcceptanceFilter() (TPCANStatus, error) {
+	status, err := p.SetAcceptanceFilter(0, MAX_STANDARD_ID, false)
+	if status != PCAN_ERROR_OK || err != nil {
+		return status, err
+	}
+	return p.SetAcceptanceFilter(0, MAX_EXTENDED_ID, true)
+}
+
+// set of message identifiers
+type idSet map[TPCANMsgID]struct{}
+
+// Configures a software filter which drops all received messages with an ID not contained in the given set
+// ids: IDs to be received, an empty or nil set disables the software filter and opens the hardware filter again
+// Note: The tightest hardware filter range covering all IDs is set as pre-filter, the software filter refines it.
+// Status and error frames are not filtered as they do not carry a CAN ID.
+// Note: Can be called while another goroutine reads, the allowlist is replaced atomically
+func (p *TPCANBus) SetSoftwareIDAllowlist(ids []TPCANMsgID) (TPCANStatus, error) {
+	if len(ids) == 0 {
+		p.idAllowlist.Store(nil)
+		return p.ResetFilter()
+	}
+
+	allowlist := make(idSet, len(ids))
+	fromID, toID := ids[0], ids[0]
+	for _, id := range ids {
+		allowlist[id] = struct{}{}
+		fromID = min(fromID, id)
+		toID = max(toID, id)
+	}
+
+	// program hardware pre-filter, extended mode is needed as soon as a 29-bit identifier is part of the set
+	mode := PCAN_MODE_STANDARD
+	if toID.IsExtendedRange() {
+		mode = PCAN_MODE_EXTENDED
+	}
+	status, err := p.SetFilter(fromID, toID, mode)
+	if status != PCAN_ERROR_OK || err != nil {
+		return status, err
+	}
+
+	p.idAllowlist.Store(&allowlist)
+	return status, err
+}
+
+// Configures a software filter applied on received messages after the hardware filter
+// filter: Filter deciding which messages are returned by Read(), nil disables the software filter
+// Note: Messages dropped by the filter are skipped by all read functions
+// Note: Safe to call while another goroutine reads, the filter is switched between two messages
+func (p *TPCANBus) SetSoftwareFilter(filter Filter) {
+	if filter == nil {
+		p.softwareFilter.Store(nil)
+		return
+	}
+	p.softwareFilter.Store(&filter)
+}
+
+// Attaches a cache which is updated with every data message returned by Read()
+// cache: Cache to update, nil detaches the current cache
+// Note: Safe to call while another goroutine reads, a message is stored in the cache attached when it is read
+func (p *TPCANBus) SetSnapshotCache(cache *SnapshotCache) {
+	p.snapshot.Store(cache)
+}
+
+// checks if a received message passes the software allowlist and the software filter
+func (p *TPCANBus) isAllowed(msg *TPCANMsg) bool {
+	if allowlist := p.idAllowlist.Load(); allowlist != nil && msg.MsgType&(PCAN_MESSAGE_STATUS|PCAN_MESSAGE_ERRFRAME) == 0 {
+		if _, ok := (*allowlist)[msg.ID]; !ok {
+			return false
+		}
+	}
+	filter := p.softwareFilter.Load()
+	return filter == nil || (*filter).Allow(msg)
+}
+
 // Retrieves a PCAN Channel value using a defined parameter value type
 // param: The TPCANParameter parameter to get
 // Note: Parameters can be present or not according with the kind of Hardware (PCAN Channel) being used.
@@ -241,6 +1069,43 @@ func (p *TPCANBus) SetParameter(param TPCANParameter, val TPCANParameterValue) (
 	return status, err
 }
 
+// Configures several PCAN Channel values, applied in ascending order of the parameters
+// params: Values by parameter
+// Note: Stops at the first failing parameter and returns it together with its status
+func (p *TPCANBus) ApplyParameters(params map[TPCANParameter]TPCANParameterValue) (TPCANParameter, TPCANStatus, error) {
+	keys := make([]TPCANParameter, 0, len(params))
+	for param := range params {
+		keys = append(keys, param)
+	}
+	slices.Sort(keys)
+
+	for _, param := range keys {
+		status, err := p.SetParameter(param, params[param])
+		if status != PCAN_ERROR_OK || err != nil {
+			return param, status, err
+		}
+	}
+	return 0, PCAN_ERROR_OK, nil
+}
+
+// Retrieves several PCAN Channel values
+// params: Parameters to get
+// Note: Stops at the first failing parameter and returns its status with an error naming the parameter
+func (p *TPCANBus) ReadParameters(params []TPCANParameter) (TPCANStatus, map[TPCANParameter]TPCANParameterValue, error) {
+	values := make(map[TPCANParameter]TPCANParameterValue, len(params))
+	for _, param := range params {
+		status, val, err := p.GetParameter(param)
+		if err != nil {
+			return status, values, fmt.Errorf("reading parameter %v failed: %w", param, err)
+		}
+		if status != PCAN_ERROR_OK {
+			return status, values, fmt.Errorf("reading parameter %v failed with status 0x%X", param, status)
+		}
+		values[param] = val
+	}
+	return PCAN_ERROR_OK, values, nil
+}
+
 // Retrieves a PCAN Channel value
 // param: The TPCANParameter parameter to get
 // Note: Parameters can be present or not according with the kind
@@ -288,96 +1153,278 @@ func (p *TPCANBus) SetAllowEchoFrames(allowEchoFrames bool) (TPCANStatus, error)
 	return p.SetParameter(PCAN_ALLOW_ECHO_FRAMES, conv[allowEchoFrames])
 }
 
+// Enables or disables the listen-only mode, in which the CAN controller does not take part in the bus communication
+// listenOnly: Only listens on the bus if set to true
+func (p *TPCANBus) SetListenOnly(listenOnly bool) (TPCANStatus, error) {
+	var conv = map[bool]TPCANParameterValue{false: PCAN_PARAMETER_OFF, true: PCAN_PARAMETER_ON}
+	return p.SetParameter(PCAN_LISTEN_ONLY, conv[listenOnly])
+}
+
+// Configures listen-only mode, echo frames and RTR frame reception together
+// mode: Combined mode, see ModeOptions for the valid combinations
+// Note: Invalid combinations are rejected with PCAN_ERROR_ILLPARAMVAL before any parameter is changed
+func (p *TPCANBus) SetMode(mode ModeOptions) (TPCANStatus, error) {
+	if err := mode.Validate(); err != nil {
+		return PCAN_ERROR_ILLPARAMVAL, err
+	}
+
+	// echo is disabled before entering listen-only mode and enabled after leaving it
+	setListenOnly := func() (TPCANStatus, error) { return p.SetListenOnly(mode.ListenOnly) }
+	setEcho := func() (TPCANStatus, error) { return p.SetAllowEchoFrames(mode.EchoFrames) }
+	setRTR := func() (TPCANStatus, error) { return p.SetAllowRTRFrames(mode.RTRFrames) }
+	setters := []func() (TPCANStatus, error){setEcho, setListenOnly, setRTR}
+	if mode.EchoFrames {
+		setters = []func() (TPCANStatus, error){setListenOnly, setEcho, setRTR}
+	}
+
+	var status TPCANStatus
+	var err error
+	for _, set := range setters {
+		status, err = set()
+		if status != PCAN_ERROR_OK || err != nil {
+			return status, err
+		}
+	}
+	return status, err
+}
+
+// Turns on or off the 5-Volt power supply of the device (only available on some PC-Card and ISA devices)
+// power5V: Supplies 5 Volt if set to true
+func (p *TPCANBus) SetPower5V(power5V bool) (TPCANStatus, error) {
+	var conv = map[bool]TPCANParameterValue{false: PCAN_PARAMETER_OFF, true: PCAN_PARAMETER_ON}
+	return p.SetParameter(PCAN_5VOLTS_POWER, conv[power5V])
+}
+
 // Turn on or off flashing of the device's LED for physical identification purposes
 func (p *TPCANBus) SetLEDState(ledState bool) (TPCANStatus, error) {
 	var conv = map[bool]TPCANParameterValue{false: PCAN_PARAMETER_OFF, true: PCAN_PARAMETER_ON}
 	return p.SetParameter(PCAN_CHANNEL_IDENTIFYING, conv[ledState])
 }
 
+// Returns if the device's LED is currently flashing for physical identification purposes
+// Note: The identifying parameter is the only LED related parameter exposed by the PCAN-Basic driver.
+// On hardware without this feature PCAN_ERROR_ILLPARAMTYPE is returned and the state is false
+func (p *TPCANBus) GetLEDState() (TPCANStatus, bool, error) {
+	status, val, err := p.GetParameter(PCAN_CHANNEL_IDENTIFYING)
+	if status != PCAN_ERROR_OK || err != nil {
+		return status, false, err
+	}
+	return status, val == PCAN_PARAMETER_ON, err
+}
+
+// Returns the device number used to distinguish several devices of the same type
+func (p *TPCANBus) GetDeviceNumber() (TPCANStatus, uint32, error) {
+	status, val, err := p.GetParameter(PCAN_DEVICE_NUMBER)
+	return status, uint32(val), err
+}
+
+// Stores a device number in the device used to distinguish several devices of the same type
+// number: Device number which is kept by the device after a replug
+// Note: On hardware not able to store a device number PCAN_ERROR_ILLPARAMTYPE is returned
+func (p *TPCANBus) SetDeviceNumber(number uint32) (TPCANStatus, error) {
+	return p.SetParameter(PCAN_DEVICE_NUMBER, TPCANParameterValue(number))
+}
+
+// Returns the baud rate the channel is configured with and whether it is known
+// Note: If the bus was not created with a baud rate, the driver's configured bit rate (PCAN_BITRATE_INFO) is used
+// Note: A stored baud rate not matching the nominal bus speed reported by the driver is reported as not known
+// Note: FD channels have no BTR0BTR1 baud rate and are always reported as not known, query their bus speeds instead
+func (p *TPCANBus) ConfiguredBaudrate() (TPCANBaudrate, bool) {
+	if p.Baudrate == 0 {
+		status, val, err := p.GetParameter(PCAN_BITRATE_INFO)
+		if status != PCAN_ERROR_OK || err != nil || val == 0 {
+			return 0, false
+		}
+		return TPCANBaudrate(val), true
+	}
+
+	// cross check with the driver, the predefined bit rates are rounded so a small deviation is accepted
+	status, nominal, err := p.GetParameter(PCAN_BUSSPEED_NOMINAL)
+	expected, ok := BaudrateBitsPerSecond(p.Baudrate)
+	if status == PCAN_ERROR_OK && err == nil && ok && nominal != 0 {
+		deviation := int64(nominal) - int64(expected)
+		if max(deviation, -deviation)*100 > int64(expected) {
+			return p.Baudrate, false
+		}
+	}
+	return p.Baudrate, true
+}
+
+// Returns the receive (REC) and transmit (TEC) error counters of the CAN controller
+// Note: The PCAN-Basic driver does not expose the error counters, so ErrNotSupported is always returned.
+// The error levels reported by GetStatus() (PCAN_ERROR_BUSLIGHT, PCAN_ERROR_BUSHEAVY, PCAN_ERROR_BUSPASSIVE,
+// PCAN_ERROR_BUSOFF) are derived from these counters and can be used to trend the bus quality instead.
+// Error frames carry the counters at the time of the error, see TPCANMsg.ErrorFrameInfo().
+func (p *TPCANBus) ErrorCounters() (TPCANStatus, uint8, uint8, error) {
+	return PCAN_ERROR_ILLPARAMTYPE, 0, 0, ErrNotSupported
+}
+
+// Returns the timing information of the device used to interpret timestamps of received messages
+// Note: The PCAN-Basic driver neither exposes the timer epoch nor the current device time, so only the fixed
+// resolution of TPCANTimestamp is returned together with ErrNotSupported. Use a reference message as done by
+// MultiCapture to map timestamps to host time.
+func (p *TPCANBus) DeviceTimerInfo() (TPCANStatus, TimerInfo, error) {
+	return PCAN_ERROR_ILLPARAMTYPE, TimerInfo{Resolution: time.Microsecond}, ErrNotSupported
+}
+
+// Returns the capabilities of the PCAN device
+func (p *TPCANBus) Features() (TPCANStatus, ChannelFeatures, error) {
+	status, val, err := p.GetParameter(PCAN_CHANNEL_FEATURES)
+	return status, DecodeFeatures(TPCANFeatureValue(val)), err
+}
+
 // Returns the channel condition as a level for availablity
 func (p *TPCANBus) GetChannelCondition() (TPCANStatus, TPCANCHannelCondition, error) {
 	state, val, err := p.GetParameter(PCAN_CHANNEL_CONDITION)
 	return state, TPCANCHannelCondition(val), err
 }
 
+// Returns if the hardware of the PCAN Channel is still present without causing any bus traffic
+// Note: This checks the device presence (e.g. an unplugged USB adapter), use GetStatus() for the bus health
+func (p *TPCANBus) IsConnected() (TPCANStatus, bool, error) {
+	status, cond, err := p.GetChannelCondition()
+	if status != PCAN_ERROR_OK || err != nil {
+		return status, false, err
+	}
+	return status, cond != PCAN_CHANNEL_UNAVAILABLE, err
+}
+
 // Starts recording a trace on given path with a max file size in MB
 // maxFileSize: trace file is splitted in files with this maximum size of file in MB; set to zero to have a infinite large trace file (max is 100 MB)
 // Note: A trace file only gets filled if the Recv() function is called!
 func (p *TPCANBus) StartTrace(filePath string, maxFileSize uint32) (TPCANStatus, error) {
-	if maxFileSize > MAX_TRACE_FILE_SIZE_ACCEPTED {
+	return p.StartTraceWithConfig(filePath, TraceConfig{MaxFileSize: maxFileSize, IncludeDate: true, IncludeTime: true, Overwrite: true})
+}
+
+// Starts recording a trace on given path with a custom trace configuration
+// cfg: Configuration of the trace file naming and storing mode
+// Note: The driver has no limit for the amount of segmented trace files. If cfg.MaxFiles is set, the oldest trace
+// files of this channel in the directory are deleted by the package while tracing, files of other channels are kept.
+// cfg.MaxFiles requires cfg.MaxFileSize, as only a segmented trace writes several files.
+// Note: A trace file only gets filled if the Recv() function is called!
+func (p *TPCANBus) StartTraceWithConfig(filePath string, cfg TraceConfig) (TPCANStatus, error) {
+	if cfg.MaxFileSize > MAX_TRACE_FILE_SIZE_ACCEPTED {
 		return PCAN_ERROR_UNKNOWN, fmt.Errorf("maximum size of a trace file is %v MB", MAX_TRACE_FILE_SIZE_ACCEPTED)
 	}
-
-	// configure trace configuration (only file size is set, the other options are always used)
-	cfg := TRACE_FILE_DATE | TRACE_FILE_TIME | TRACE_FILE_OVERWRITE
-	if maxFileSize > 0 {
-		cfg |= TRACE_FILE_SEGMENTED
-	} else {
-		cfg |= TRACE_FILE_SINGLE
+	if cfg.MaxFiles > 0 && cfg.MaxFileSize == 0 {
+		return PCAN_ERROR_ILLPARAMVAL, errors.New("a maximum amount of trace files requires a maximum file size")
 	}
-	state, err := p.SetParameter(PCAN_TRACE_CONFIGURE, TPCANParameterValue(cfg))
+	channel := traceChannelName(p.Handle)
+	if cfg.MaxFiles > 0 && channel == "" {
+		return PCAN_ERROR_ILLPARAMVAL, fmt.Errorf("trace files of handle 0x%X can not be told apart from other files", p.Handle)
+	}
+
+	// configure trace configuration
+	state, err := p.SetParameter(PCAN_TRACE_CONFIGURE, TPCANParameterValue(cfg.Flags()))
 	if err != nil || state != PCAN_ERROR_OK {
 		return state, err
 	}
-	if maxFileSize > 0 {
-		state, err := p.SetValue(PCAN_TRACE_SIZE, unsafe.Pointer(&maxFileSize), 4)
+	if cfg.MaxFileSize > 0 {
+		state, err := p.SetValue(PCAN_TRACE_SIZE, unsafe.Pointer(&cfg.MaxFileSize), 4)
 		if err != nil || state != PCAN_ERROR_OK {
 			return state, err
 		}
 	}
 
 	// configure trace file path
-	if len(filePath) > MAX_LENGHT_STRING_BUFFER {
-		return PCAN_ERROR_UNKNOWN, fmt.Errorf("filepath exceeds max length of %v", MAX_LENGHT_STRING_BUFFER)
-	}
-
-	// convert path to a fixed buffer size as pcan wants it that way
-	var buffer [MAX_LENGHT_STRING_BUFFER]byte
-	for i := range filePath {
-		buffer[i] = byte(filePath[i])
-	}
-	state, err = p.SetValue(PCAN_TRACE_LOCATION, unsafe.Pointer(&buffer), uint32(unsafe.Sizeof(buffer)))
+	state, err = p.setStringValue(PCAN_TRACE_LOCATION, filePath)
 	if err != nil || state != PCAN_ERROR_OK {
 		return state, err
 	}
 
 	// start tracing
 	state, err = p.SetParameter(PCAN_TRACE_STATUS, PCAN_PARAMETER_ON)
+	if err != nil || state != PCAN_ERROR_OK {
+		return state, err
+	}
+
+	if cfg.MaxFiles > 0 {
+		p.startTracePruning(filePath, channel, cfg.MaxFiles)
+	}
 	return state, err
 }
 
 // Stops recording currently running trace
 func (p *TPCANBus) StopTrace() (TPCANStatus, error) {
+	p.stopTracePruning()
 	return p.SetParameter(PCAN_TRACE_STATUS, PCAN_PARAMETER_OFF)
 }
 
-// prepare WaitForSingleObject implementation when waiting for CAN messages (currently only windows support)
+// Returns if a trace is currently recorded
+func (p *TPCANBus) TraceStatus() (TPCANStatus, bool, error) {
+	status, val, err := p.GetParameter(PCAN_TRACE_STATUS)
+	if status != PCAN_ERROR_OK || err != nil {
+		return status, false, err
+	}
+	return status, val == PCAN_PARAMETER_ON, err
+}
+
+// Returns the directory path the trace files are written to
+func (p *TPCANBus) TraceLocation() (TPCANStatus, string, error) {
+	return p.getStringValue(PCAN_TRACE_LOCATION)
+}
+
+// writes a string parameter as null terminated ANSI string in a fixed buffer size as pcan wants it that way
+func (p *TPCANBus) setStringValue(param TPCANParameter, value string) (TPCANStatus, error) {
+	var buffer [MAX_LENGHT_STRING_BUFFER]byte
+	if err := encodeAnsiString(value, buffer[:]); err != nil {
+		return PCAN_ERROR_ILLPARAMVAL, err
+	}
+	return p.SetValue(param, unsafe.Pointer(&buffer), uint32(unsafe.Sizeof(buffer)))
+}
+
+// reads a string parameter into a fixed buffer size as pcan wants it that way and trims it at the terminator
+func (p *TPCANBus) getStringValue(param TPCANParameter) (TPCANStatus, string, error) {
+	var buffer [MAX_LENGHT_STRING_BUFFER]byte
+	status, err := p.GetValue(param, unsafe.Pointer(&buffer), uint32(unsafe.Sizeof(buffer)))
+	if status != PCAN_ERROR_OK || err != nil {
+		return status, "", err
+	}
+	return status, cString(buffer[:]), err
+}
+
+// prepare the receive event signaled by the driver when waiting for CAN messages (currently only windows support)
+// Note: Without UseReceiveEvents or if the event can not be created or registered, the channel is polled
 func (p *TPCANBus) initializeRecvEvent() {
 	p.recvEvent = 0
-	if hasEvents {
-		modkernel32, errLoad := syscall.LoadLibrary("kernel32.dll")
-		procCreateEvent, errOpen := syscall.GetProcAddress(modkernel32, "CreateEventW")
-		if errLoad == nil && errOpen == nil && procCreateEvent != 0 {
-			r0, _, errno := syscall.SyscallN(procCreateEvent)
-			if errno == 0 && r0 != 0 && syscall.Handle(r0) != syscall.InvalidHandle {
-				p.recvEvent = syscall.Handle(r0)
-				retVal, errVal := p.SetParameter(PCAN_RECEIVE_EVENT, TPCANParameterValue(r0))
-				if retVal != PCAN_ERROR_OK || errVal != nil {
-					hasEvents = false
-					_ = syscall.CloseHandle(p.recvEvent)
-					p.recvEvent = 0
-				}
-			}
-		}
-		// just for safety
-		if p.recvEvent == 0 || p.recvEvent == syscall.InvalidHandle {
-			hasEvents = false
-		}
+	if !UseReceiveEvents {
+		return
+	}
+	event, err := newEvent()
+	if err != nil || event == 0 {
+		return
 	}
+	status, err := p.setRecvEventValue(event)
+	if status != PCAN_ERROR_OK || err != nil {
+		_ = freeEvent(event)
+		return
+	}
+	p.recvEvent = event
+}
+
+// registers an event handle at the driver, the value has the size of a handle on every architecture
+func (p *TPCANBus) setRecvEventValue(event eventHandle) (TPCANStatus, error) {
+	return p.SetValue(PCAN_RECEIVE_EVENT, unsafe.Pointer(&event), uint32(unsafe.Sizeof(event)))
+}
+
+// closes the receive event if it was created by this package and forgets it, the connection mutex must be held
+func (p *TPCANBus) closeRecvEvent() error {
+	event, external := p.recvEvent, p.recvEventExternal
+	p.recvEvent = 0
+	p.recvEventExternal = false
+	if event == 0 || external {
+		return nil
+	}
+	return freeEvent(event)
 }
 
 // Uninitializes all PCAN Channels initialized by CAN_Initialize
 func ShutdownAllHandles() (TPCANStatus, error) {
+	initializedBusesMu.Lock()
+	clear(initializedBuses)
+	clear(initializedFDBuses)
+	initializedBusesMu.Unlock()
+
 	return APIUninitialize(PCAN_NONEBUS)
 }
 
@@ -417,6 +1464,139 @@ func AttachedChannels() ([]TPCANHandle, error) {
 	return attachedChannels, nil
 }
 
+// Returns if the channel of a not yet initialized handle supports CAN FD, e.g. to decide between InitializeFD and InitializeBasic
+// handle: The handle of a PCAN Channel
+// Note: Returns ErrNoChannel if no hardware is present for the handle
+func IsFDCapable(handle TPCANHandle) (bool, error) {
+	if err := loadAPIImplicitly(); err != nil {
+		return false, err
+	}
+
+	var condition TPCANCHannelCondition
+	status, err := APIGetValue(handle, PCAN_CHANNEL_CONDITION, unsafe.Pointer(&condition), uint32(unsafe.Sizeof(condition)))
+	if err != nil {
+		return false, err
+	}
+	if status != PCAN_ERROR_OK || condition == PCAN_CHANNEL_UNAVAILABLE {
+		return false, fmt.Errorf("%w: handle 0x%X", ErrNoChannel, handle)
+	}
+
+	var features TPCANFeatureValue
+	status, err = APIGetValue(handle, PCAN_CHANNEL_FEATURES, unsafe.Pointer(&features), uint32(unsafe.Sizeof(features)))
+	if err != nil {
+		return false, err
+	}
+	if status != PCAN_ERROR_OK {
+		return false, fmt.Errorf("reading features of handle 0x%X failed with status 0x%X", handle, status)
+	}
+	return features&FEATURE_FD_CAPABLE != 0, nil
+}
+
+// Returns the description of a status code as text in the given language
+// status: Status code returned by any API call
+// language: Language of the text, LanguageNeutral uses the language of the operating system
+// Note: The text is cut at its terminator, converted from the system code page and trailing control characters are removed
+func GetErrorString(status TPCANStatus, language TPCANLanguage) (string, error) {
+	if err := loadAPIImplicitly(); err != nil {
+		return "", err
+	}
+
+	ret, buffer, err := APIGetErrorText(status, language)
+	if err != nil {
+		return "", err
+	}
+	if ret != PCAN_ERROR_OK {
+		return "", fmt.Errorf("getting text of status 0x%X failed with status 0x%X", status, ret)
+	}
+	return strings.TrimRightFunc(decodeAnsiString(buffer[:]), func(r rune) bool {
+		return unicode.IsControl(r) || unicode.IsSpace(r) || r == utf8.RuneError
+	}), nil
+}
+
+// Returns the part number of the device of a handle, the handle does not need to be initialized
+func GetDevicePartNumber(handle TPCANHandle) (TPCANStatus, string, error) {
+	return getHandleStringValue(handle, PCAN_DEVICE_PART_NUMBER)
+}
+
+// Returns the globally unique identifier of the device of a handle, the handle does not need to be initialized
+// Note: The identifier is derived from the serial number of the device and is only available for USB devices
+func GetDeviceGUID(handle TPCANHandle) (TPCANStatus, string, error) {
+	return getHandleStringValue(handle, PCAN_DEVICE_GUID)
+}
+
+// Initializes the channel of the attached device with the given serial, independent of the enumeration order or USB port
+// serial: Unique identifier of the device as returned by GetDeviceGUID, compared case-insensitive and without braces
+// baudRate: The speed for the communication (BTR0BTR1 code)
+// Note: Returns ErrNoChannel if no attached device has the serial and ErrMultipleChannels if the device has several
+// channels, use LookUpChannelWithOptions with DeviceGUID and ControllerNumber for those
+func OpenBySerial(serial string, baudRate TPCANBaudrate) (*TPCANBus, error) {
+	if err := loadAPIImplicitly(); err != nil {
+		return nil, err
+	}
+	channels, err := AttachedChannels()
+	if err != nil {
+		return nil, err
+	}
+
+	normalize := func(id string) string { return strings.ToLower(strings.Trim(strings.TrimSpace(id), "{}")) }
+	var matches []TPCANHandle
+	for _, handle := range channels {
+		status, guid, err := GetDeviceGUID(handle)
+		if status == PCAN_ERROR_OK && err == nil && guid != "" && normalize(guid) == normalize(serial) {
+			matches = append(matches, handle)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w: no attached device with serial %v", ErrNoChannel, serial)
+	case 1:
+	default:
+		return nil, fmt.Errorf("%w: device with serial %v has channels %v", ErrMultipleChannels, serial, matches)
+	}
+
+	status, bus, err := InitializeBasic(matches[0], baudRate)
+	if err != nil {
+		return nil, err
+	}
+	if status != PCAN_ERROR_OK {
+		return nil, fmt.Errorf("initializing channel 0x%X with serial %v failed with status 0x%X", matches[0], serial, status)
+	}
+	return bus, nil
+}
+
+// reads a string parameter of a handle which does not need to be initialized
+func getHandleStringValue(handle TPCANHandle, param TPCANParameter) (TPCANStatus, string, error) {
+	if err := loadAPIImplicitly(); err != nil {
+		return PCAN_ERROR_NODRIVER, "", err
+	}
+	var buffer [MAX_LENGHT_STRING_BUFFER]byte
+	status, err := APIGetValue(handle, param, unsafe.Pointer(&buffer), uint32(unsafe.Sizeof(buffer)))
+	if status != PCAN_ERROR_OK || err != nil {
+		return status, "", err
+	}
+	return status, cString(buffer[:]), err
+}
+
+// Returns the handle of the only attached PCAN channel
+// Note: Returns ErrNoChannel if no channel is attached and ErrMultipleChannels if the choice is ambiguous
+func SingleChannel() (TPCANHandle, error) {
+	if err := loadAPIImplicitly(); err != nil {
+		return PCAN_NONEBUS, err
+	}
+	channels, err := AttachedChannels()
+	if err != nil {
+		return PCAN_NONEBUS, err
+	}
+	switch len(channels) {
+	case 0:
+		return PCAN_NONEBUS, ErrNoChannel
+	case 1:
+		return channels[0], nil
+	default:
+		return PCAN_NONEBUS, fmt.Errorf("%w: %v", ErrMultipleChannels, channels)
+	}
+}
+
 // Returns list of all existing PCAN channels on a system in a single call, regardless of their current availability
 // TODO This function is not working correctly, as the given information does not matched connected hardware, use AttachedChannels instead
 func AttachedChannels_Extended() ([]TPCANChannelInformation, error) {
@@ -441,3 +1621,46 @@ func AttachedChannels_Extended() ([]TPCANChannelInformation, error) {
 func LookUpChannel(deviceType string, deviceID string, controllerNumber string, ipAdress string) (TPCANStatus, TPCANHandle, error) {
 	return APILookUpChannel(deviceType, deviceID, controllerNumber, ipAdress)
 }
+
+// Finds a PCAN-Basic Channel that matches with the given parameter string
+// parameters: A comma separated string contained pairs of parameter-name/value to be matched within a PCAN-Basic Channel (e.g. "devicetype=PCAN_USB, deviceid=1")
+func LookUpChannelRaw(parameters string) (TPCANStatus, TPCANHandle, error) {
+	return APILookUpChannelRaw(parameters)
+}
+
+// Finds a PCAN-Basic Channel that matches with the given criteria
+// opts: Criteria of the channel, empty fields are omitted
+// Note: The driver has no lookup by channel features, so RequiredFeatures is checked on the found channel
+func LookUpChannelWithOptions(opts LookUpOptions) (TPCANStatus, TPCANHandle, error) {
+	criteria := []struct {
+		key   TPCANLookupParameter
+		value string
+	}{
+		{LOOKUP_DEVICE_TYPE, opts.DeviceType},
+		{LOOKUP_DEVICE_ID, opts.DeviceID},
+		{LOOKUP_CONTROLLER_NUMBER, opts.ControllerNumber},
+		{LOOKUP_IP_ADDRESS, opts.IPAddress},
+		{LOOKUP_DEVICE_GUID, opts.DeviceGUID},
+	}
+	var pairs []string
+	for _, c := range criteria {
+		if c.value != "" {
+			pairs = append(pairs, string(c.key)+"="+c.value)
+		}
+	}
+
+	status, handle, err := APILookUpChannelRaw(strings.Join(pairs, ", "))
+	if status != PCAN_ERROR_OK || err != nil || opts.RequiredFeatures == 0 {
+		return status, handle, err
+	}
+
+	var features TPCANFeatureValue
+	status, err = APIGetValue(handle, PCAN_CHANNEL_FEATURES, unsafe.Pointer(&features), uint32(unsafe.Sizeof(features)))
+	if status != PCAN_ERROR_OK || err != nil {
+		return status, handle, err
+	}
+	if features&opts.RequiredFeatures != opts.RequiredFeatures {
+		return PCAN_ERROR_UNKNOWN, handle, fmt.Errorf("channel 0x%X does not have the required features 0x%X", handle, opts.RequiredFeatures)
+	}
+	return status, handle, err
+}
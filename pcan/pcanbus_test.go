@@ -0,0 +1,569 @@
+package pcan
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+/* Tests of the bus functions against stubbed api calls. */
+
+// received frame returned by a stubbed read
+type stubFrame struct {
+	status    TPCANStatus
+	msg       TPCANMsg
+	timestamp TPCANTimestamp
+	err       error
+}
+
+// replaces the read of the receive queue for the duration of a test, the frames are returned in order and
+// PCAN_ERROR_QRCVEMPTY afterwards
+func stubRead(t *testing.T, frames ...stubFrame) {
+	t.Helper()
+	var mutex sync.Mutex
+	oldRead := apiRead
+	apiRead = func(handle TPCANHandle) (TPCANStatus, TPCANMsg, TPCANTimestamp, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if len(frames) == 0 {
+			return PCAN_ERROR_QRCVEMPTY, TPCANMsg{}, TPCANTimestamp{}, nil
+		}
+		frame := frames[0]
+		frames = frames[1:]
+		return frame.status, frame.msg, frame.timestamp, frame.err
+	}
+	t.Cleanup(func() { apiRead = oldRead })
+}
+
+// returns a received data frame
+func dataFrame(id TPCANMsgID, data ...byte) stubFrame {
+	msg := TPCANMsg{ID: id, MsgType: PCAN_MESSAGE_STANDARD, DLC: uint8(len(data))}
+	copy(msg.Data[:], data)
+	return stubFrame{status: PCAN_ERROR_OK, msg: msg}
+}
+
+func TestSetSoftwareIDAllowlist(t *testing.T) {
+	type call struct {
+		name  string
+		param TPCANParameter
+		value TPCANParameterValue
+		from  TPCANMsgID
+		to    TPCANMsgID
+		mode  TPCANMode
+	}
+	var calls []call
+	stubProc(t, &pHandleSetValue, func(a ...uintptr) (uintptr, uintptr, error) {
+		calls = append(calls, call{name: "CAN_SetValue", param: TPCANParameter(a[1]), value: *argPtr[TPCANParameterValue](a[2])})
+		return uintptr(PCAN_ERROR_OK), 0, nil
+	})
+	stubProc(t, &pHandleFilterMessages, func(a ...uintptr) (uintptr, uintptr, error) {
+		calls = append(calls, call{name: "CAN_FilterMessages", from: TPCANMsgID(a[1]), to: TPCANMsgID(a[2]), mode: TPCANMode(a[3])})
+		return uintptr(PCAN_ERROR_OK), 0, nil
+	})
+
+	bus := &TPCANBus{Handle: PCAN_USBBUS1}
+	if status, err := bus.SetSoftwareIDAllowlist([]TPCANMsgID{0x300, 0x100, 0x200}); status != PCAN_ERROR_OK || err != nil {
+		t.Fatalf("SetSoftwareIDAllowlist() = %v, %v", status, err)
+	}
+
+	// the filter must be closed before the range is opened, closing it afterwards would drop everything
+	want := []call{
+		{name: "CAN_SetValue", param: PCAN_MESSAGE_FILTER, value: TPCANParameterValue(PCAN_FILTER_CLOSE)},
+		{name: "CAN_FilterMessages", from: 0x100, to: 0x300, mode: PCAN_MODE_STANDARD},
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("driver calls = %+v, want %+v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("driver call %v = %+v, want %+v", i, calls[i], want[i])
+		}
+	}
+
+	stubRead(t, dataFrame(0x100, 1), dataFrame(0x150, 2), dataFrame(0x300, 3),
+		stubFrame{status: PCAN_ERROR_OK, msg: TPCANMsg{ID: 0x150, MsgType: PCAN_MESSAGE_STATUS}})
+	var ids []TPCANMsgID
+	for {
+		status, msg, _, err := bus.Read()
+		if err != nil {
+			t.Fatalf("Read() = %v", err)
+		}
+		if status == PCAN_ERROR_QRCVEMPTY {
+			break
+		}
+		ids = append(ids, msg.ID)
+	}
+	if len(ids) != 3 || ids[0] != 0x100 || ids[1] != 0x300 || ids[2] != 0x150 {
+		t.Errorf("received IDs = %#x, want 0x100 and 0x300 and the status frame", ids)
+	}
+}
+
+func TestSetSoftwareIDAllowlistWhileReading(t *testing.T) {
+	stubProc(t, &pHandleSetValue, func(a ...uintptr) (uintptr, uintptr, error) { return uintptr(PCAN_ERROR_OK), 0, nil })
+	stubProc(t, &pHandleFilterMessages, func(a ...uintptr) (uintptr, uintptr, error) { return uintptr(PCAN_ERROR_OK), 0, nil })
+	oldRead := apiRead
+	apiRead = func(handle TPCANHandle) (TPCANStatus, TPCANMsg, TPCANTimestamp, error) {
+		return PCAN_ERROR_OK, TPCANMsg{ID: 0x100, MsgType: PCAN_MESSAGE_STANDARD}, TPCANTimestamp{}, nil
+	}
+	t.Cleanup(func() { apiRead = oldRead })
+
+	bus := &TPCANBus{Handle: PCAN_USBBUS1}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			_, _, _, _ = bus.Read()
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		_, _ = bus.SetSoftwareIDAllowlist([]TPCANMsgID{0x100, TPCANMsgID(0x200 + i%10)})
+		_, _ = bus.SetSoftwareIDAllowlist(nil)
+	}
+	<-done
+}
+
+// registers a bus as initialized for the duration of a test
+func registerBus(t *testing.T, bus *TPCANBus) {
+	t.Helper()
+	initializedBusesMu.Lock()
+	initializedBuses[bus.Handle] = bus
+	initializedBusesMu.Unlock()
+	t.Cleanup(func() {
+		initializedBusesMu.Lock()
+		delete(initializedBuses, bus.Handle)
+		initializedBusesMu.Unlock()
+	})
+}
+
+// counts the calls of driver procedures for the duration of a test, every call succeeds
+func countCalls(t *testing.T, procs map[string]**apiProc) map[string]int {
+	t.Helper()
+	calls := map[string]int{}
+	for name, proc := range procs {
+		stubProc(t, proc, func(a ...uintptr) (uintptr, uintptr, error) {
+			calls[name]++
+			return uintptr(PCAN_ERROR_OK), 0, nil
+		})
+	}
+	return calls
+}
+
+func TestInitializeBasicWithOptionsExistingBus(t *testing.T) {
+	calls := countCalls(t, map[string]**apiProc{
+		"CAN_Initialize":   &pHandleInitialize,
+		"CAN_Uninitialize": &pHandleUninitialize,
+		"CAN_Reset":        &pHandleReset,
+		"CAN_SetValue":     &pHandleSetValue,
+	})
+	existing := &TPCANBus{Handle: PCAN_USBBUS1, Baudrate: PCAN_BAUD_500K, HWType: PCAN_DEFAULT_HW_TYPE,
+		IOPort: PCAN_DEFAULT_IO_PORT, Interrupt: PCAN_DEFAULT_INTERRUPT}
+	registerBus(t, existing)
+
+	status, bus, err := InitializeBasicWithOptions(PCAN_USBBUS1, PCAN_BAUD_500K, InitOptions{ListenOnly: true})
+	if !errors.Is(err, ErrAlreadyInitialized) || bus != nil || status == PCAN_ERROR_OK {
+		t.Fatalf("InitializeBasicWithOptions() = %v, %v, %v, want ErrAlreadyInitialized", status, bus, err)
+	}
+	if len(calls) != 0 {
+		t.Errorf("driver calls = %v, the existing bus must not be touched", calls)
+	}
+	initializedBusesMu.Lock()
+	registered := initializedBuses[PCAN_USBBUS1]
+	initializedBusesMu.Unlock()
+	if registered != existing {
+		t.Error("existing bus is not registered anymore")
+	}
+}
+
+func TestInitializeBasicWithOptionsNewBus(t *testing.T) {
+	calls := countCalls(t, map[string]**apiProc{
+		"CAN_Initialize":   &pHandleInitialize,
+		"CAN_Uninitialize": &pHandleUninitialize,
+		"CAN_Reset":        &pHandleReset,
+		"CAN_SetValue":     &pHandleSetValue,
+	})
+	t.Cleanup(func() {
+		initializedBusesMu.Lock()
+		delete(initializedBuses, PCAN_USBBUS2)
+		initializedBusesMu.Unlock()
+	})
+
+	status, bus, err := InitializeBasicWithOptions(PCAN_USBBUS2, PCAN_BAUD_500K, InitOptions{ListenOnly: true})
+	if status != PCAN_ERROR_OK || err != nil || bus == nil {
+		t.Fatalf("InitializeBasicWithOptions() = %v, %v, %v", status, bus, err)
+	}
+	if calls["CAN_Initialize"] != 1 || calls["CAN_SetValue"] != 5 || calls["CAN_Reset"] != 1 || calls["CAN_Uninitialize"] != 0 {
+		t.Errorf("driver calls = %v, want one initialization, five options and one reset", calls)
+	}
+}
+
+func TestReadClampsClassicDLC(t *testing.T) {
+	frame := dataFrame(0x123, 1, 2, 3, 4, 5, 6, 7, 8)
+	frame.msg.DLC = 15
+	stubRead(t, frame, dataFrame(0x124, 1))
+
+	bus := &TPCANBus{Handle: PCAN_USBBUS1}
+	status, msg, _, err := bus.Read()
+	if status != PCAN_ERROR_OK || err != nil {
+		t.Fatalf("Read() = %v, %v", status, err)
+	}
+	if msg.DLC != LENGTH_DATA_CAN_MESSAGE {
+		t.Errorf("DLC = %v, want it clamped to %v", msg.DLC, LENGTH_DATA_CAN_MESSAGE)
+	}
+	if _, msg, _, _ = bus.Read(); msg.DLC != 1 {
+		t.Errorf("DLC = %v of a valid message, want 1", msg.DLC)
+	}
+	if bus.DLCAnomalies() != 1 {
+		t.Errorf("DLCAnomalies() = %v, want 1", bus.DLCAnomalies())
+	}
+}
+
+func TestReadFDValidatesDLC(t *testing.T) {
+	oldRead := apiReadFD
+	t.Cleanup(func() { apiReadFD = oldRead })
+
+	tests := []struct {
+		dlc     uint8
+		wantErr bool
+	}{
+		{0, false},
+		{8, false},
+		{9, false},
+		{15, false},
+		{16, true},
+		{255, true},
+	}
+	bus := &TPCANBusFD{Handle: PCAN_USBBUS1}
+	for _, test := range tests {
+		apiReadFD = func(handle TPCANHandle) (TPCANStatus, TPCANMsgFD, TPCANTimestampFD, error) {
+			return PCAN_ERROR_OK, TPCANMsgFD{ID: 0x123, MsgType: PCAN_MESSAGE_FD, DLC: test.dlc}, 0, nil
+		}
+		status, msg, _, err := bus.ReadFD()
+		if test.wantErr {
+			if status != PCAN_ERROR_ILLDATA || err == nil || msg != nil {
+				t.Errorf("ReadFD() with DLC %v = %v, %v, %v, want PCAN_ERROR_ILLDATA and an error", test.dlc, status, msg, err)
+			}
+			continue
+		}
+		if status != PCAN_ERROR_OK || err != nil || msg == nil || msg.DLC != test.dlc {
+			t.Errorf("ReadFD() with DLC %v = %v, %v, %v, want the message unchanged", test.dlc, status, msg, err)
+		}
+	}
+}
+
+func TestReadCountsRxOverflow(t *testing.T) {
+	overrun := dataFrame(0x123, 1)
+	overrun.status = PCAN_ERROR_QOVERRUN
+	controllerOverrun := dataFrame(0x124, 2)
+	controllerOverrun.status = PCAN_ERROR_OVERRUN
+	stubRead(t, dataFrame(0x122), overrun, controllerOverrun, stubFrame{status: PCAN_ERROR_BUSLIGHT})
+
+	bus := &TPCANBus{Handle: PCAN_USBBUS1}
+	wantOverflow := []bool{false, true, true, false}
+	for i, want := range wantOverflow {
+		status, msg, _, _ := bus.Read()
+		if IsRxOverflow(status) != want {
+			t.Errorf("read %v: IsRxOverflow(%#x) = %v, want %v", i, status, !want, want)
+		}
+		if want && msg == nil {
+			t.Errorf("read %v: message read together with the overflow is missing", i)
+		}
+	}
+	if bus.RxOverflows() != 2 {
+		t.Errorf("RxOverflows() = %v, want 2", bus.RxOverflows())
+	}
+}
+
+func TestReadFullBufferError(t *testing.T) {
+	errRead := errors.New("device removed")
+	stubRead(t, dataFrame(0x100, 1), dataFrame(0x101, 2),
+		stubFrame{status: PCAN_ERROR_ILLHW, err: errRead}, dataFrame(0x102, 3))
+
+	bus := &TPCANBus{Handle: PCAN_USBBUS1}
+	msgs, timestamps, err := bus.ReadFullBuffer(0)
+	if !errors.Is(err, errRead) {
+		t.Errorf("ReadFullBuffer() = %v, want the injected error", err)
+	}
+	if len(msgs) != 2 || len(timestamps) != 2 || msgs[0].ID != 0x100 || msgs[1].ID != 0x101 {
+		t.Errorf("ReadFullBuffer() = %+v, want the two messages read before the error", msgs)
+	}
+}
+
+func TestReadFullBufferStatus(t *testing.T) {
+	stubRead(t, dataFrame(0x100, 1), stubFrame{status: PCAN_ERROR_ILLHW}, dataFrame(0x102, 3))
+
+	bus := &TPCANBus{Handle: PCAN_USBBUS1}
+	msgs, _, err := bus.ReadFullBuffer(0)
+	if err == nil {
+		t.Error("ReadFullBuffer() succeeded on a failing status")
+	}
+	if len(msgs) != 1 || msgs[0].ID != 0x100 {
+		t.Errorf("ReadFullBuffer() = %+v, want the message read before the failing status", msgs)
+	}
+}
+
+func TestInitializeTwiceCreatesOneEvent(t *testing.T) {
+	oldUse := UseReceiveEvents
+	UseReceiveEvents = true
+	t.Cleanup(func() { UseReceiveEvents = oldUse })
+
+	var mutex sync.Mutex
+	live := map[eventHandle]bool{}
+	created := 0
+	oldCreate, oldFree := newEvent, freeEvent
+	newEvent = func() (eventHandle, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		created++
+		live[eventHandle(created)] = true
+		return eventHandle(created), nil
+	}
+	freeEvent = func(event eventHandle) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		delete(live, event)
+		return nil
+	}
+	t.Cleanup(func() { newEvent, freeEvent = oldCreate, oldFree })
+	calls := countCalls(t, map[string]**apiProc{
+		"CAN_Initialize":   &pHandleInitialize,
+		"CAN_Uninitialize": &pHandleUninitialize,
+		"CAN_SetValue":     &pHandleSetValue,
+	})
+
+	// initialized twice from two goroutines
+	buses := make(chan *TPCANBus, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, bus, err := InitializeBasic(PCAN_USBBUS4, PCAN_BAUD_500K)
+			if err != nil {
+				t.Error(err)
+			}
+			buses <- bus
+		}()
+	}
+	first, second := <-buses, <-buses
+	if first == nil || first != second {
+		t.Fatalf("InitializeBasic() returned %p and %p, want the same bus", first, second)
+	}
+	if calls["CAN_Initialize"] != 1 || created != 1 {
+		t.Errorf("initialized %v times and created %v events, want one each", calls["CAN_Initialize"], created)
+	}
+
+	if _, err := first.Reconnect(); err != nil {
+		t.Fatal(err)
+	}
+	if created != 1 {
+		t.Errorf("created %v events after reconnecting, want the event to be kept", created)
+	}
+	if _, err := first.Uninitialize(); err != nil {
+		t.Fatal(err)
+	}
+	if len(live) != 0 {
+		t.Errorf("events %v are still open after uninitializing", live)
+	}
+}
+
+func TestReadReturnsIndependentCopies(t *testing.T) {
+	const count = 1000
+	frames := make([]stubFrame, count)
+	for i := range frames {
+		frames[i] = dataFrame(TPCANMsgID(i), byte(i), byte(i>>8))
+		frames[i].timestamp = TPCANTimestamp{Millis: uint32(i)}
+	}
+	stubRead(t, frames...)
+
+	bus := &TPCANBus{Handle: PCAN_USBBUS1}
+	msgs := make([]*TPCANMsg, 0, count)
+	timestamps := make([]*TPCANTimestamp, 0, count)
+	for i := 0; i < count; i++ {
+		_, msg, timestamp, err := bus.Read()
+		if err != nil || msg == nil {
+			t.Fatalf("Read() %v = %v, %v", i, msg, err)
+		}
+		msgs = append(msgs, msg)
+		timestamps = append(timestamps, timestamp)
+	}
+
+	// mutating one message must leave all others unchanged
+	for i := range msgs {
+		msgs[i].Data[0] = 0xFF
+		msgs[i].ID = 0x7FF
+		timestamps[i].Millis = 0
+		if i+1 < count && (msgs[i+1].ID != TPCANMsgID(i+1) || msgs[i+1].Data[0] != byte(i+1) || timestamps[i+1].Millis != uint32(i+1)) {
+			t.Fatalf("mutating message %v changed message %v to %+v", i, i+1, msgs[i+1])
+		}
+	}
+	for i := 1; i < count; i++ {
+		if msgs[i] == msgs[i-1] || timestamps[i] == timestamps[i-1] {
+			t.Fatalf("reads %v and %v returned the same pointer", i-1, i)
+		}
+	}
+}
+
+func TestReadCopiesDriverMemory(t *testing.T) {
+	// the stubbed driver keeps writing into the buffer it got last, like a driver reusing its memory
+	var last *TPCANMsg
+	next := 0
+	stubProc(t, &pHandleRead, func(a ...uintptr) (uintptr, uintptr, error) {
+		if last != nil {
+			last.ID = 0x7FF
+		}
+		last = argPtr[TPCANMsg](a[1])
+		*last = TPCANMsg{ID: TPCANMsgID(next), MsgType: PCAN_MESSAGE_STANDARD, DLC: 1, Data: [8]byte{byte(next)}}
+		next++
+		return uintptr(PCAN_ERROR_OK), 0, nil
+	})
+
+	bus := &TPCANBus{Handle: PCAN_USBBUS1}
+	var msgs []*TPCANMsg
+	for i := 0; i < 1000; i++ {
+		_, msg, _, err := bus.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, msg)
+	}
+	for i, msg := range msgs {
+		if msg.ID != TPCANMsgID(i) || msg.Data[0] != byte(i) {
+			t.Fatalf("message %v = %+v, changed by a later read", i, msg)
+		}
+	}
+}
+
+// replaces the driver calls made by Shutdown() besides the read, returns the amount of uninitializations
+func stubShutdown(t *testing.T) *atomic.Int32 {
+	t.Helper()
+	var uninitialized atomic.Int32
+	stubProc(t, &pHandleGetValue, func(a ...uintptr) (uintptr, uintptr, error) { return uintptr(PCAN_ERROR_OK), 0, nil })
+	stubProc(t, &pHandleUninitialize, func(a ...uintptr) (uintptr, uintptr, error) {
+		uninitialized.Add(1)
+		return uintptr(PCAN_ERROR_OK), 0, nil
+	})
+	return &uninitialized
+}
+
+func TestShutdownDrainIsBounded(t *testing.T) {
+	uninitialized := stubShutdown(t)
+	// a busy bus never empties the receive queue
+	oldRead := apiRead
+	apiRead = func(handle TPCANHandle) (TPCANStatus, TPCANMsg, TPCANTimestamp, error) {
+		frame := dataFrame(0x100, 1)
+		return frame.status, frame.msg, frame.timestamp, nil
+	}
+	t.Cleanup(func() { apiRead = oldRead })
+
+	drained := 0
+	bus := &TPCANBus{Handle: PCAN_USBBUS1}
+	if err := bus.Shutdown(func(*TPCANMsg) { drained++ }); err != nil {
+		t.Fatalf("Shutdown() = %v", err)
+	}
+	if drained == 0 || drained > SHUTDOWN_DRAIN_LIMIT {
+		t.Errorf("drained %v messages, want 1..%v", drained, SHUTDOWN_DRAIN_LIMIT)
+	}
+	if uninitialized.Load() != 1 {
+		t.Errorf("channel was uninitialized %v times, want once", uninitialized.Load())
+	}
+}
+
+func TestShutdownConcurrent(t *testing.T) {
+	uninitialized := stubShutdown(t)
+	stubRead(t)
+
+	bus := &TPCANBus{Handle: PCAN_USBBUS1}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = bus.Shutdown(nil)
+		}()
+	}
+	wg.Wait()
+	if uninitialized.Load() != 1 {
+		t.Errorf("channel was uninitialized %v times, want once", uninitialized.Load())
+	}
+}
+
+// replaces the read of the receive queue by an endless stream of frames with the identifiers 1 and 2 alternating
+func stubAlternatingRead(t *testing.T) {
+	t.Helper()
+	var next atomic.Uint32
+	oldRead := apiRead
+	apiRead = func(handle TPCANHandle) (TPCANStatus, TPCANMsg, TPCANTimestamp, error) {
+		frame := dataFrame(TPCANMsgID(next.Add(1)%2+1), 1)
+		return frame.status, frame.msg, frame.timestamp, nil
+	}
+	t.Cleanup(func() { apiRead = oldRead })
+}
+
+func TestSetSoftwareFilterWhileReading(t *testing.T) {
+	stubAlternatingRead(t)
+	bus := &TPCANBus{Handle: PCAN_USBBUS1}
+	onlyTwo := FilterFunc(func(msg *TPCANMsg) bool { return msg.ID == 2 })
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			if _, msg, _, err := bus.Read(); err != nil || msg == nil {
+				t.Errorf("Read() = %v, %v", msg, err)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		if i%2 == 0 {
+			bus.SetSoftwareFilter(onlyTwo)
+		} else {
+			bus.SetSoftwareFilter(nil)
+		}
+	}
+	<-done
+
+	bus.SetSoftwareFilter(onlyTwo)
+	for i := 0; i < 4; i++ {
+		if _, msg, _, _ := bus.Read(); msg == nil || msg.ID != 2 {
+			t.Fatalf("Read() = %+v, want only identifier 2", msg)
+		}
+	}
+	bus.SetSoftwareFilter(nil)
+	_, first, _, _ := bus.Read()
+	_, second, _, _ := bus.Read()
+	if first == nil || second == nil || first.ID == second.ID {
+		t.Errorf("Read() = %+v, %+v, want both identifiers without a filter", first, second)
+	}
+}
+
+func TestSetSnapshotCacheWhileReading(t *testing.T) {
+	stubAlternatingRead(t)
+	bus := &TPCANBus{Handle: PCAN_USBBUS1}
+	first, second := NewSnapshotCache(), NewSnapshotCache()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			_, _, _, _ = bus.Read()
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		if i%2 == 0 {
+			bus.SetSnapshotCache(first)
+		} else {
+			bus.SetSnapshotCache(nil)
+		}
+	}
+	<-done
+
+	bus.SetSnapshotCache(second)
+	_, msg, _, _ := bus.Read()
+	if cached, _, ok := second.Get(msg.ID); !ok || cached.ID != msg.ID {
+		t.Errorf("Get(0x%X) = %+v, %v, want the message read last", msg.ID, cached, ok)
+	}
+	bus.SetSnapshotCache(nil)
+	_, msg, _, _ = bus.Read()
+	if _, _, ok := second.Get(msg.ID); ok {
+		t.Errorf("message 0x%X was stored in a detached cache", msg.ID)
+	}
+}
@@ -0,0 +1,101 @@
+package pcan
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+/* Transmitting of queued CAN messages in the order they would win the bus arbitration. */
+
+const priorityWriterFullTimeout = time.Second // maximum time to wait for a full transmit queue before giving up
+
+// Queues CAN messages and transmits them ordered by their arbitration priority
+type PriorityWriter struct {
+	bus *TPCANBus
+
+	mutex   sync.Mutex
+	pending []queuedMsg
+	seq     uint64 // insertion counter keeping the order of messages with the same priority
+}
+
+// message waiting for transmission
+type queuedMsg struct {
+	msg TPCANMsg
+	seq uint64
+}
+
+// Creates a priority writer for the given bus
+func NewPriorityWriter(bus *TPCANBus) *PriorityWriter {
+	return &PriorityWriter{bus: bus}
+}
+
+// Adds a copy of the message to the transmit queue, safe for concurrent use
+func (w *PriorityWriter) Enqueue(msg *TPCANMsg) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.pending = append(w.pending, queuedMsg{msg: *msg, seq: w.seq})
+	w.seq++
+}
+
+// Returns the amount of messages waiting for transmission
+func (w *PriorityWriter) Len() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return len(w.pending)
+}
+
+// Transmits all queued messages, highest arbitration priority (lowest ID) first
+// Note: If the transmit queue of the driver is full, sending is retried until priorityWriterFullTimeout elapsed.
+// On an error the messages not sent yet are queued again.
+func (w *PriorityWriter) Flush() (TPCANStatus, error) {
+	w.mutex.Lock()
+	batch := w.pending
+	w.pending = nil
+	w.mutex.Unlock()
+
+	sort.Slice(batch, func(a, b int) bool {
+		pa, pb := arbitrationKey(&batch[a].msg), arbitrationKey(&batch[b].msg)
+		if pa != pb {
+			return pa < pb
+		}
+		return batch[a].seq < batch[b].seq
+	})
+
+	for i := range batch {
+		status, err := w.write(&batch[i].msg)
+		if status != PCAN_ERROR_OK || err != nil {
+			w.requeue(batch[i:])
+			return status, err
+		}
+	}
+	return PCAN_ERROR_OK, nil
+}
+
+// writes a message and waits while the transmit queue is full
+func (w *PriorityWriter) write(msg *TPCANMsg) (TPCANStatus, error) {
+	endTime := time.Now().Add(priorityWriterFullTimeout)
+	for {
+		status, err := w.bus.Write(msg)
+		if err != nil || status&(PCAN_ERROR_QXMTFULL|PCAN_ERROR_XMTFULL) == 0 || time.Now().After(endTime) {
+			return status, err
+		}
+		time.Sleep(DEFAULT_POLL_INTERVAL)
+	}
+}
+
+// puts unsent messages back in front of messages enqueued in the meantime
+func (w *PriorityWriter) requeue(msgs []queuedMsg) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.pending = append(append([]queuedMsg(nil), msgs...), w.pending...)
+}
+
+// returns a key ordering messages like the bus arbitration, a lower key wins
+// Note: The 11-bit base identifier decides first, a standard frame wins against an extended frame with the same base
+func arbitrationKey(msg *TPCANMsg) uint32 {
+	if msg.MsgType&PCAN_MESSAGE_EXTENDED != 0 {
+		return uint32(msg.ID>>18&0x7FF)<<19 | 1<<18 | uint32(msg.ID&0x3FFFF)
+	}
+	return uint32(msg.ID&0x7FF) << 19
+}
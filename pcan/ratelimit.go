@@ -0,0 +1,71 @@
+package pcan
+
+import (
+	"sync"
+	"time"
+)
+
+/* Rate limited transmitting of CAN messages using a token bucket. */
+
+// Writes CAN messages to a bus without exceeding a configured amount of frames per second
+type RateLimitedWriter struct {
+	bus *TPCANBus
+
+	mutex  sync.Mutex
+	fps    int       // allowed frames per second, zero or below disables limiting
+	tokens float64   // currently available tokens
+	last   time.Time // last time the tokens were refilled
+}
+
+// Creates a rate limited writer for the given bus
+// fps: Maximum amount of frames per second, zero or below disables limiting
+func NewRateLimitedWriter(bus *TPCANBus, fps int) *RateLimitedWriter {
+	return &RateLimitedWriter{bus: bus, fps: fps, tokens: 1, last: time.Now()}
+}
+
+// Changes the maximum amount of frames per second, may be called while other goroutines are writing
+// fps: Maximum amount of frames per second, zero or below disables limiting
+func (w *RateLimitedWriter) SetRate(fps int) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.refill(time.Now())
+	w.fps = fps
+}
+
+// Returns the currently configured maximum amount of frames per second
+func (w *RateLimitedWriter) Rate() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.fps
+}
+
+// Transmits a CAN message as soon as the rate limit allows it
+// msg: A Message struct with the message to be sent
+func (w *RateLimitedWriter) Write(msg *TPCANMsg) (TPCANStatus, error) {
+	for {
+		w.mutex.Lock()
+		if w.fps <= 0 {
+			w.mutex.Unlock()
+			break
+		}
+		now := time.Now()
+		w.refill(now)
+		if w.tokens >= 1 {
+			w.tokens--
+			w.mutex.Unlock()
+			break
+		}
+		wait := time.Duration((1 - w.tokens) / float64(w.fps) * float64(time.Second))
+		w.mutex.Unlock()
+		time.Sleep(wait)
+	}
+	return w.bus.Write(msg)
+}
+
+// adds the tokens earned since the last refill, the bucket holds at most a single token to avoid bursts
+func (w *RateLimitedWriter) refill(now time.Time) {
+	if w.fps > 0 {
+		w.tokens = min(1, w.tokens+now.Sub(w.last).Seconds()*float64(w.fps))
+	}
+	w.last = now
+}
@@ -0,0 +1,145 @@
+package pcan
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+/* Tests of the selection of the read strategy and of waiting with it. */
+
+// replaces the event functions for the duration of a test
+func stubEvents(t *testing.T, create func() (eventHandle, error), wait func(eventHandle, uint32) (waitResult, error)) {
+	t.Helper()
+	oldCreate, oldWait := newEvent, waitForEvent
+	newEvent, waitForEvent = create, wait
+	t.Cleanup(func() { newEvent, waitForEvent = oldCreate, oldWait })
+}
+
+func TestInitializeRecvEvent(t *testing.T) {
+	const event eventHandle = 7
+
+	tests := []struct {
+		name         string
+		useEvents    bool
+		createErr    error
+		setStatus    TPCANStatus
+		wantStrategy ReadStrategy
+	}{
+		{"events enabled", true, nil, PCAN_ERROR_OK, ReadStrategyEvent},
+		{"events disabled", false, nil, PCAN_ERROR_OK, ReadStrategyPolling},
+		{"event not created", true, ErrNotSupported, PCAN_ERROR_OK, ReadStrategyPolling},
+		{"event not registered", true, nil, PCAN_ERROR_ILLPARAMTYPE, ReadStrategyPolling},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			oldUse := UseReceiveEvents
+			UseReceiveEvents = test.useEvents
+			t.Cleanup(func() { UseReceiveEvents = oldUse })
+
+			var registered eventHandle
+			stubProc(t, &pHandleSetValue, func(a ...uintptr) (uintptr, uintptr, error) {
+				registered = *argPtr[eventHandle](a[2])
+				return uintptr(test.setStatus), 0, nil
+			})
+			stubEvents(t, func() (eventHandle, error) { return event, test.createErr }, nil)
+
+			bus := &TPCANBus{Handle: PCAN_USBBUS1}
+			bus.initializeRecvEvent()
+			if got := bus.ReadStrategy(); got != test.wantStrategy {
+				t.Errorf("ReadStrategy() = %v, want %v", got, test.wantStrategy)
+			}
+			if test.wantStrategy == ReadStrategyEvent && registered != event {
+				t.Errorf("registered event = %v, want %v", registered, event)
+			}
+		})
+	}
+}
+
+func TestReadWithTimeoutWaitsForEvent(t *testing.T) {
+	const event eventHandle = 7
+	var waits []uint32
+	stubEvents(t, nil, func(handle eventHandle, timeout uint32) (waitResult, error) {
+		if handle != event {
+			t.Errorf("waiting for event %v, want %v", handle, event)
+		}
+		waits = append(waits, timeout)
+		return waitSignaled, nil
+	})
+	stubRead(t, stubFrame{status: PCAN_ERROR_QRCVEMPTY}, dataFrame(0x123, 1))
+
+	bus := &TPCANBus{Handle: PCAN_USBBUS1, recvEvent: event}
+	bus.SetClock(newFakeClock())
+	status, msg, _, err := bus.ReadWithTimeout(-1)
+	if status != PCAN_ERROR_OK || err != nil || msg == nil || msg.ID != 0x123 {
+		t.Fatalf("ReadWithTimeout() = %v, %v, %v", status, msg, err)
+	}
+	if len(waits) != 1 || waits[0] != infiniteTimeout {
+		t.Errorf("waited %v, want once without limit", waits)
+	}
+}
+
+func TestReadWithTimeoutEventTimeout(t *testing.T) {
+	var waits []uint32
+	stubEvents(t, nil, func(handle eventHandle, timeout uint32) (waitResult, error) {
+		waits = append(waits, timeout)
+		return waitTimeout, nil
+	})
+	stubRead(t)
+
+	bus := &TPCANBus{Handle: PCAN_USBBUS1, recvEvent: 7}
+	bus.SetClock(newFakeClock())
+	status, msg, _, err := bus.ReadWithTimeout(50)
+	if status != PCAN_ERROR_QRCVEMPTY || msg != nil || err != nil {
+		t.Fatalf("ReadWithTimeout() = %v, %v, %v, want an empty queue", status, msg, err)
+	}
+	if len(waits) != 1 || waits[0] != 50 {
+		t.Errorf("waited %v, want once for 50 ms", waits)
+	}
+}
+
+func TestReadWithTimeoutEventFailed(t *testing.T) {
+	errWait := errors.New("wait failed")
+	stubEvents(t, nil, func(handle eventHandle, timeout uint32) (waitResult, error) { return waitFailed, errWait })
+	stubRead(t)
+
+	bus := &TPCANBus{Handle: PCAN_USBBUS1, recvEvent: 7}
+	if _, _, _, err := bus.ReadWithTimeout(50); !errors.Is(err, errWait) {
+		t.Errorf("ReadWithTimeout() = %v, want the error of waiting", err)
+	}
+}
+
+func TestReadWithTimeoutPollsWithoutEvent(t *testing.T) {
+	stubEvents(t, nil, func(handle eventHandle, timeout uint32) (waitResult, error) {
+		t.Fatal("waiting for an event without a receive event")
+		return waitFailed, nil
+	})
+	stubRead(t, stubFrame{status: PCAN_ERROR_QRCVEMPTY}, dataFrame(0x123, 1))
+
+	bus := &TPCANBus{Handle: PCAN_USBBUS1}
+	clock := newFakeClock()
+	bus.SetClock(clock)
+	if _, msg, _, _ := bus.ReadWithTimeout(-1); msg == nil || msg.ID != 0x123 {
+		t.Fatalf("ReadWithTimeout() = %v, want the message", msg)
+	}
+	if clock.slept != DEFAULT_POLL_INTERVAL {
+		t.Errorf("slept %v, want one poll interval", clock.slept)
+	}
+}
+
+// clock which only advances by sleeping
+type fakeClock struct {
+	now   time.Time
+	slept time.Duration // sum of all sleeps
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.now = c.now.Add(d)
+	c.slept += d
+}
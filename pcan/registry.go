@@ -0,0 +1,52 @@
+package pcan
+
+import (
+	"fmt"
+	"sync"
+)
+
+/* Mapping of logical network names to PCAN Channels. */
+
+// Maps logical network names to the handle and baud rate of a PCAN Channel, safe for concurrent use
+type Registry struct {
+	mutex    sync.RWMutex
+	networks map[string]registryEntry
+}
+
+// channel settings of a registered network
+type registryEntry struct {
+	handle   TPCANHandle
+	baudRate TPCANBaudrate
+}
+
+// Creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{networks: make(map[string]registryEntry)}
+}
+
+// Registers a network name, an already registered name is overwritten
+// name: Logical name of the network (e.g. "Powertrain")
+// handle: The handle of the PCAN Channel connected to the network
+// baudRate: The speed for the communication (BTR0BTR1 code)
+func (r *Registry) Register(name string, handle TPCANHandle, baudRate TPCANBaudrate) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.networks[name] = registryEntry{handle: handle, baudRate: baudRate}
+}
+
+// Returns the handle and baud rate registered for a network name
+func (r *Registry) Lookup(name string) (TPCANHandle, TPCANBaudrate, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	entry, ok := r.networks[name]
+	return entry.handle, entry.baudRate, ok
+}
+
+// Initializes the PCAN Channel registered for a network name
+func (r *Registry) Open(name string) (TPCANStatus, *TPCANBus, error) {
+	handle, baudRate, ok := r.Lookup(name)
+	if !ok {
+		return PCAN_ERROR_UNKNOWN, nil, fmt.Errorf("network %q is not registered", name)
+	}
+	return InitializeBasic(handle, baudRate)
+}
@@ -0,0 +1,63 @@
+package pcan
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+/* Replaying of recorded messages with their original timing. */
+
+// Writes recorded messages on the bus keeping the delays between their timestamps
+// msgs: Messages to replay, e.g. read by ReadCSV
+// timestamps: Receive timestamps of the messages, must have the same length as msgs
+// opts: Looping and speed of the replay
+// Note: Delays are measured from the start of every iteration, so slow writes do not accumulate a drift
+// Note: Returns ctx.Err() if ctx is done before the replay finished
+func Replay(ctx context.Context, p *TPCANBus, msgs []TPCANMsg, timestamps []TPCANTimestamp, opts ReplayOptions) error {
+	if len(msgs) != len(timestamps) {
+		return fmt.Errorf("amount of messages (%v) and timestamps (%v) differ", len(msgs), len(timestamps))
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	speed := opts.SpeedFactor
+	if speed <= 0 {
+		speed = 1
+	}
+	iterations := 1
+	if opts.Loop {
+		iterations = opts.Count
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for i := 0; iterations == 0 || i < iterations; i++ {
+		start := time.Now()
+		for j := range msgs {
+			offset := time.Duration(float64(TimestampDelta(timestamps[0], timestamps[j])) / speed)
+			if wait := time.Until(start.Add(offset)); wait > 0 {
+				timer.Reset(wait)
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-timer.C:
+				}
+			} else if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			msg := msgs[j]
+			status, err := p.WriteContext(ctx, &msg)
+			if err != nil {
+				return err
+			}
+			if status != PCAN_ERROR_OK {
+				return fmt.Errorf("replaying message 0x%X failed with status 0x%X", msg.ID, status)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package pcan
+
+import (
+	"errors"
+	"time"
+)
+
+/* Retrying of API calls failing with statuses which only signal a temporary condition. */
+
+// Configures how often and with which wait time WithRetry() repeats an operation
+type RetryPolicy struct {
+	Attempts   int                    // Maximum amount of attempts including the first one, zero or below makes no attempt
+	Backoff    time.Duration          // Wait time before the first retry, doubled with every further retry
+	MaxBackoff time.Duration          // Upper bound of the wait time between two attempts; zero for no bound
+	Retryable  func(TPCANStatus) bool // Decides if a status is worth another attempt; nil selects IsTransient()
+}
+
+// Checks if a status only signals a temporary condition, so repeating the call later can succeed
+// Note: Full transmit queues, hardware or nets used by another client and missing driver resources count as
+// transient. An empty receive queue (PCAN_ERROR_QRCVEMPTY) is no failure and therefore not transient.
+func IsTransient(status TPCANStatus) bool {
+	switch status {
+	case PCAN_ERROR_QXMTFULL, PCAN_ERROR_XMTFULL, PCAN_ERROR_HWINUSE, PCAN_ERROR_NETINUSE, PCAN_ERROR_RESOURCE:
+		return true
+	}
+	return false
+}
+
+// Repeats an operation while it returns a transient status, waiting with exponential backoff between the attempts
+// op: Operation to run, e.g. a closure calling Write or SetValue
+// policy: Amount of attempts, backoff and classification of the statuses to retry
+// Note: The status and error of the last attempt are returned, also if all attempts were used up
+func WithRetry(op func() (TPCANStatus, error), policy RetryPolicy) (TPCANStatus, error) {
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = IsTransient
+	}
+
+	var status TPCANStatus = PCAN_ERROR_UNKNOWN
+	var err error = errors.New("no attempt made")
+	backoff := policy.Backoff
+	for i := 0; i < policy.Attempts; i++ {
+		if i > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+			if policy.MaxBackoff > 0 {
+				backoff = min(backoff, policy.MaxBackoff)
+			}
+		}
+		status, err = op()
+		if !retryable(status) {
+			return status, err
+		}
+	}
+	return status, err
+}
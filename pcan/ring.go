@@ -0,0 +1,91 @@
+package pcan
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+/* Flight recorder keeping the most recent messages of a bus in a ring buffer. */
+
+// A message retained by a RingCapture
+type RingFrame struct {
+	Msg       TPCANMsg       // Received message
+	Timestamp TPCANTimestamp // Driver timestamp of the message
+	Received  time.Time      // Host time the message was read at
+}
+
+// Continuously reads a bus and retains only the last messages, e.g. to dump the context of a fault
+type RingCapture struct {
+	bus *TPCANBus
+
+	mutex  sync.Mutex
+	frames []RingFrame // ring buffer, next is the oldest frame once the buffer is full
+	next   int         // position the next frame is written to
+	full   bool        // all positions of the buffer hold a frame
+	total  uint64      // amount of frames captured since the start
+}
+
+// Creates a ring capture retaining the last size messages of an already initialized bus
+// Note: A size below one retains a single message
+func NewRingCapture(bus *TPCANBus, size int) *RingCapture {
+	return &RingCapture{bus: bus, frames: make([]RingFrame, max(size, 1))}
+}
+
+// Reads messages from the bus into the ring buffer until ctx is cancelled or a read error occurs
+// Note: All received messages are retained, including status and error frames
+func (r *RingCapture) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		_, msg, timestamp, err := r.bus.ReadWithTimeout(monitorReadTimeout)
+		if err != nil {
+			return err
+		}
+		if msg != nil && timestamp != nil {
+			r.add(RingFrame{Msg: *msg, Timestamp: *timestamp, Received: time.Now()})
+		}
+	}
+}
+
+// stores a frame, overwriting the oldest one if the buffer is full
+func (r *RingCapture) add(frame RingFrame) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.frames[r.next] = frame
+	r.next = (r.next + 1) % len(r.frames)
+	r.full = r.full || r.next == 0
+	r.total++
+}
+
+// Returns a copy of the retained messages, oldest first
+// Note: Safe to call while Run is capturing, the copy is not changed by later messages
+func (r *RingCapture) Snapshot() []RingFrame {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.full {
+		return append([]RingFrame(nil), r.frames[:r.next]...)
+	}
+	snapshot := make([]RingFrame, 0, len(r.frames))
+	snapshot = append(snapshot, r.frames[r.next:]...)
+	return append(snapshot, r.frames[:r.next]...)
+}
+
+// Returns the amount of messages captured since the start, including the ones already overwritten
+func (r *RingCapture) Total() uint64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.total
+}
+
+// Removes all retained messages
+func (r *RingCapture) Clear() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	clear(r.frames)
+	r.next, r.full = 0, false
+}
@@ -0,0 +1,61 @@
+package pcan
+
+import (
+	"time"
+)
+
+/* Inventory of the messages observed on a bus. */
+
+// Statistics of a message identifier observed by ScanBus
+type FrameStats struct {
+	Count     int                           // Amount of received messages
+	First     TPCANTimestamp                // Timestamp of the first received message
+	Last      TPCANTimestamp                // Timestamp of the last received message
+	MinPeriod time.Duration                 // Smallest time between two consecutive messages, zero if received only once
+	MaxPeriod time.Duration                 // Largest time between two consecutive messages, zero if received only once
+	MsgType   TPCANMessageType              // Type of the last received message
+	DLC       uint8                         // Data Length Code of the last received message
+	Data      [LENGTH_DATA_CAN_MESSAGE]byte // Data of the last received message
+}
+
+// Listens on the bus for the given duration and returns statistics for every observed message identifier
+// duration: Time to listen on the bus
+// Note: Status and error frames are not part of the inventory
+func ScanBus(p *TPCANBus, duration time.Duration) (map[TPCANMsgID]FrameStats, error) {
+	stats := make(map[TPCANMsgID]FrameStats)
+	endTime := time.Now().Add(duration)
+
+	for {
+		remaining := time.Until(endTime)
+		if remaining <= 0 {
+			return stats, nil
+		}
+
+		_, msg, timestamp, err := p.ReadWithTimeout(int(max(remaining.Milliseconds(), 1)))
+		if err != nil {
+			return stats, err
+		}
+		if msg == nil || msg.MsgType&(PCAN_MESSAGE_STATUS|PCAN_MESSAGE_ERRFRAME) != 0 {
+			continue
+		}
+
+		entry, seen := stats[msg.ID]
+		if !seen {
+			entry.First = *timestamp
+		} else {
+			period := TimestampDelta(entry.Last, *timestamp)
+			if entry.Count == 1 || period < entry.MinPeriod {
+				entry.MinPeriod = period
+			}
+			if period > entry.MaxPeriod {
+				entry.MaxPeriod = period
+			}
+		}
+		entry.Count++
+		entry.Last = *timestamp
+		entry.MsgType = msg.MsgType
+		entry.DLC = msg.DLC
+		entry.Data = msg.Data
+		stats[msg.ID] = entry
+	}
+}
@@ -0,0 +1,165 @@
+package pcan
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+/* Cyclic transmission of messages with individual periods, e.g. for a residual bus simulation. */
+
+const schedulerFullBackoff = time.Millisecond // wait time before sending again after the transmit queue was full
+
+// Transmits registered messages cyclically with their own period from a single timer loop
+type Scheduler struct {
+	mutex sync.Mutex
+	slots map[TPCANMsgID]*scheduledMsg
+	wake  chan struct{} // signals a running loop that the slots changed
+}
+
+// message sent cyclically by the scheduler
+type scheduledMsg struct {
+	msg    TPCANMsg
+	period time.Duration
+	next   time.Time // time the message is due next, zero if it was not sent yet
+}
+
+// Creates an empty scheduler
+func NewScheduler() *Scheduler {
+	return &Scheduler{slots: map[TPCANMsgID]*scheduledMsg{}, wake: make(chan struct{}, 1)}
+}
+
+// Registers a message sent every period, an already registered message with the same ID is replaced
+// id: Identifier of the message
+// extended: Message has a 29-bit identifier
+// data: Payload of up to 8 bytes
+// period: Cycle time of the message, must be above zero
+// Note: A newly registered message is sent with the next loop iteration, safe for concurrent use with Run
+func (s *Scheduler) Register(id TPCANMsgID, extended bool, data []byte, period time.Duration) error {
+	if period <= 0 {
+		return fmt.Errorf("period of message 0x%X must be above zero", id)
+	}
+	msg, err := newMsg(id, extended, data)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	s.slots[id] = &scheduledMsg{msg: msg, period: period}
+	s.mutex.Unlock()
+	s.notify()
+	return nil
+}
+
+// Removes a registered message
+func (s *Scheduler) Unregister(id TPCANMsgID) {
+	s.mutex.Lock()
+	delete(s.slots, id)
+	s.mutex.Unlock()
+	s.notify()
+}
+
+// Changes the payload of a registered message without changing its schedule
+// id: Identifier of the registered message
+// data: New payload of up to 8 bytes, the DLC is set to its length
+func (s *Scheduler) UpdatePayload(id TPCANMsgID, data []byte) error {
+	if len(data) > LENGTH_DATA_CAN_MESSAGE {
+		return fmt.Errorf("data length of %v bytes exceeds maximum of %v bytes", len(data), LENGTH_DATA_CAN_MESSAGE)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	slot, found := s.slots[id]
+	if !found {
+		return fmt.Errorf("message 0x%X is not registered", id)
+	}
+	slot.msg.Data = [LENGTH_DATA_CAN_MESSAGE]byte{}
+	copy(slot.msg.Data[:], data)
+	slot.msg.DLC = uint8(len(data))
+	return nil
+}
+
+// Sends the registered messages on the bus until ctx is cancelled or writing fails
+// Note: Messages due at the same time are sent together, highest arbitration priority first
+// Note: If the transmit queue is full, the due messages are sent again after a short backoff instead of being dropped;
+// cycles missed because of that are skipped so a message is never sent in a burst
+func (s *Scheduler) Run(ctx context.Context, p *TPCANBus) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		now := time.Now()
+		due, wait := s.dueMessages(now)
+
+		for _, msg := range due {
+			status, err := p.Write(&msg)
+			if err != nil {
+				return err
+			}
+			if status == PCAN_ERROR_QXMTFULL || status == PCAN_ERROR_XMTFULL {
+				wait = schedulerFullBackoff
+				break
+			}
+			if status != PCAN_ERROR_OK {
+				return fmt.Errorf("sending scheduled message 0x%X failed with status 0x%X", msg.ID, status)
+			}
+			s.markSent(msg.ID, now)
+		}
+
+		timer.Reset(wait)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// returns copies of all messages due at the given time ordered by arbitration priority and the time until the next one is due
+func (s *Scheduler) dueMessages(now time.Time) ([]TPCANMsg, time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var due []TPCANMsg
+	wait := time.Hour // idle wait if nothing is registered, any registration wakes the loop
+	for _, slot := range s.slots {
+		if !slot.next.After(now) {
+			due = append(due, slot.msg)
+		} else {
+			wait = min(wait, slot.next.Sub(now))
+		}
+	}
+	sort.Slice(due, func(a, b int) bool { return arbitrationKey(&due[a]) < arbitrationKey(&due[b]) })
+	if len(due) > 0 {
+		wait = 0 // recomputed after the due messages were sent
+	}
+	return due, wait
+}
+
+// schedules the next transmission of a sent message, skipping cycles which were missed
+func (s *Scheduler) markSent(id TPCANMsgID, now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	slot, found := s.slots[id]
+	if !found {
+		return
+	}
+	if slot.next.IsZero() {
+		slot.next = now
+	}
+	slot.next = slot.next.Add(slot.period)
+	if !slot.next.After(now) {
+		slot.next = now.Add(slot.period)
+	}
+}
+
+// wakes up a running loop without blocking
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
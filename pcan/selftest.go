@@ -0,0 +1,67 @@
+package pcan
+
+import (
+	"fmt"
+	"time"
+)
+
+/* End-to-end verification of a PCAN Channel. */
+
+const selfTestTimeout = time.Second // time to wait for the echo of the self test message
+
+// message sent during the self test
+var selfTestMsg = TPCANMsg{
+	ID:      0x7E5,
+	MsgType: PCAN_MESSAGE_STANDARD,
+	DLC:     LENGTH_DATA_CAN_MESSAGE,
+	Data:    [LENGTH_DATA_CAN_MESSAGE]byte{0xA5, 0x5A, 0x01, 0x02, 0x04, 0x08, 0xF0, 0x0F}}
+
+// Verifies a PCAN Channel by sending a message and receiving its echo frame
+// handle: The handle of a PCAN Channel which is not initialized yet
+// baudRate: The speed for the communication (BTR0BTR1 code)
+// Note: The PCAN-Basic driver has no internal loopback mode, so the echo frame feature is used instead. The echo
+// is only generated if the message was sent successfully, which requires another node acknowledging it.
+// The channel is uninitialized after the test.
+// Note: Fails with ErrAlreadyInitialized if the channel is initialized already, its bus is not touched.
+func SelfTest(handle TPCANHandle, baudRate TPCANBaudrate) (TPCANStatus, bool, error) {
+	return selfTest(handle, baudRate, selfTestTimeout)
+}
+
+// verifies a PCAN Channel and waits up to timeout for the echo frame
+func selfTest(handle TPCANHandle, baudRate TPCANBaudrate, timeout time.Duration) (TPCANStatus, bool, error) {
+	status, bus, created, err := initializeBasic(handle, baudRate, nil)
+	if status != PCAN_ERROR_OK || err != nil {
+		return status, false, err
+	}
+	if !created {
+		return PCAN_ERROR_ILLOPERATION, false, fmt.Errorf("%w: handle 0x%X is in use, the self test needs an own channel", ErrAlreadyInitialized, handle)
+	}
+	defer bus.Uninitialize()
+
+	status, err = bus.SetAllowEchoFrames(true)
+	if status == PCAN_ERROR_ILLPARAMTYPE {
+		return status, false, fmt.Errorf("loopback unsupported: %w", ErrNotSupported)
+	}
+	if status != PCAN_ERROR_OK || err != nil {
+		return status, false, err
+	}
+
+	msg := selfTestMsg
+	status, err = bus.Write(&msg)
+	if status != PCAN_ERROR_OK || err != nil {
+		return status, false, err
+	}
+
+	// wait for the echo of the sent message
+	endTime := time.Now().Add(timeout)
+	for time.Now().Before(endTime) {
+		status, rx, _, err := bus.ReadWithTimeout(int(max(time.Until(endTime).Milliseconds(), 1)))
+		if err != nil {
+			return status, false, err
+		}
+		if rx != nil && rx.MsgType&PCAN_MESSAGE_ECHO != 0 && rx.ID == msg.ID && rx.DLC == msg.DLC && rx.Data == msg.Data {
+			return PCAN_ERROR_OK, true, nil
+		}
+	}
+	return PCAN_ERROR_QRCVEMPTY, false, fmt.Errorf("echo of self test message 0x%X not received within %v", msg.ID, timeout)
+}
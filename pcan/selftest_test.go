@@ -0,0 +1,86 @@
+package pcan
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+/* Tests of the self test of a PCAN Channel. */
+
+// replaces the driver calls made by SelfTest() besides the read for the duration of a test
+func stubSelfTest(t *testing.T) {
+	t.Helper()
+	countCalls(t, map[string]**apiProc{
+		"CAN_Initialize":   &pHandleInitialize,
+		"CAN_Uninitialize": &pHandleUninitialize,
+		"CAN_Reset":        &pHandleReset,
+		"CAN_SetValue":     &pHandleSetValue,
+		"CAN_Write":        &pHandleWrite,
+	})
+	oldUse := UseReceiveEvents
+	UseReceiveEvents = false
+	t.Cleanup(func() { UseReceiveEvents = oldUse })
+}
+
+func TestSelfTestReceivesEcho(t *testing.T) {
+	stubSelfTest(t)
+	echo := stubFrame{status: PCAN_ERROR_OK, msg: selfTestMsg}
+	stubRead(t, dataFrame(0x100, 1), echoFrame(echo))
+
+	status, ok, err := SelfTest(PCAN_USBBUS1, PCAN_BAUD_500K)
+	if status != PCAN_ERROR_OK || !ok || err != nil {
+		t.Errorf("SelfTest() = %v, %v, %v, want PCAN_ERROR_OK and true", status, ok, err)
+	}
+}
+
+func TestSelfTestWaitsUntilDeadline(t *testing.T) {
+	const timeout = 10 * time.Millisecond
+	stubSelfTest(t)
+
+	reads := 0
+	oldRead := apiRead
+	apiRead = func(handle TPCANHandle) (TPCANStatus, TPCANMsg, TPCANTimestamp, error) {
+		reads++
+		return PCAN_ERROR_QRCVEMPTY, TPCANMsg{}, TPCANTimestamp{}, nil
+	}
+	t.Cleanup(func() { apiRead = oldRead })
+
+	status, ok, err := selfTest(PCAN_USBBUS1, PCAN_BAUD_500K, timeout)
+	if status != PCAN_ERROR_QRCVEMPTY || ok || err == nil {
+		t.Errorf("SelfTest() = %v, %v, %v, want PCAN_ERROR_QRCVEMPTY and an error", status, ok, err)
+	}
+
+	// every read of at least one millisecond polls a few times at most, a timeout of zero would spin on the queue
+	maxReads := int(timeout/time.Millisecond+1) * int(time.Millisecond/DEFAULT_POLL_INTERVAL+2)
+	if reads > maxReads {
+		t.Errorf("receive queue read %v times, want at most %v", reads, maxReads)
+	}
+}
+
+func TestSelfTestExistingBus(t *testing.T) {
+	calls := countCalls(t, map[string]**apiProc{
+		"CAN_Initialize":   &pHandleInitialize,
+		"CAN_Uninitialize": &pHandleUninitialize,
+		"CAN_Reset":        &pHandleReset,
+		"CAN_SetValue":     &pHandleSetValue,
+		"CAN_Write":        &pHandleWrite,
+	})
+	existing := &TPCANBus{Handle: PCAN_USBBUS1, Baudrate: PCAN_BAUD_500K, HWType: PCAN_DEFAULT_HW_TYPE,
+		IOPort: PCAN_DEFAULT_IO_PORT, Interrupt: PCAN_DEFAULT_INTERRUPT}
+	registerBus(t, existing)
+
+	status, ok, err := SelfTest(PCAN_USBBUS1, PCAN_BAUD_500K)
+	if !errors.Is(err, ErrAlreadyInitialized) || ok || status == PCAN_ERROR_OK {
+		t.Fatalf("SelfTest() = %v, %v, %v, want ErrAlreadyInitialized", status, ok, err)
+	}
+	if len(calls) != 0 {
+		t.Errorf("driver calls = %v, the existing bus must not be touched", calls)
+	}
+	initializedBusesMu.Lock()
+	registered := initializedBuses[PCAN_USBBUS1]
+	initializedBusesMu.Unlock()
+	if registered != existing {
+		t.Error("existing bus is not registered anymore")
+	}
+}
@@ -0,0 +1,62 @@
+package pcan
+
+import (
+	"sync"
+	"time"
+)
+
+/* Cache of the last received message per identifier. */
+
+// Stores the most recent message of every identifier, safe for concurrent use
+type SnapshotCache struct {
+	mutex   sync.RWMutex
+	entries map[TPCANMsgID]snapshotEntry
+}
+
+// cached message with its time of reception
+type snapshotEntry struct {
+	msg      TPCANMsg
+	received time.Time
+}
+
+// Creates an empty snapshot cache
+func NewSnapshotCache() *SnapshotCache {
+	return &SnapshotCache{entries: make(map[TPCANMsgID]snapshotEntry)}
+}
+
+// Stores a message as the most recent one of its identifier
+func (c *SnapshotCache) Update(msg *TPCANMsg, received time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries[msg.ID] = snapshotEntry{msg: *msg, received: received}
+}
+
+// Returns a copy of the most recent message of an identifier and its time of reception
+func (c *SnapshotCache) Get(id TPCANMsgID) (*TPCANMsg, time.Time, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	entry, ok := c.entries[id]
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	return &entry.msg, entry.received, true
+}
+
+// Returns copies of the most recent messages of all identifiers
+func (c *SnapshotCache) Snapshot() map[TPCANMsgID]*TPCANMsg {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	snapshot := make(map[TPCANMsgID]*TPCANMsg, len(c.entries))
+	for id, entry := range c.entries {
+		msg := entry.msg
+		snapshot[id] = &msg
+	}
+	return snapshot
+}
+
+// Removes all cached messages
+func (c *SnapshotCache) Clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	clear(c.entries)
+}
@@ -0,0 +1,107 @@
+package pcan
+
+import (
+	"fmt"
+	"strings"
+)
+
+/* Errors describing non-OK status codes of API calls. */
+
+// If set to true, API calls returning a non-OK status also return a *StatusError describing the status.
+// The description is read from the driver with an additional CAN_GetErrorText call, so it is disabled by default.
+// Note: Statuses used for flow control are never returned as error: an empty receive queue (PCAN_ERROR_QRCVEMPTY),
+// receive overflows (see IsRxOverflow), a full transmit queue and the bus state returned by GetStatus.
+var EnrichStatusErrors bool = false
+
+// Error describing a non-OK status returned by an API call
+type StatusError struct {
+	Status TPCANStatus // Status returned by the API call
+	Text   string      // Description of the status as returned by the driver, empty if not available
+}
+
+// Returns the names of the status bits followed by the description of the driver, e.g. "PCAN_ERROR_BUSOFF: ..."
+func (e *StatusError) Error() string {
+	if e.Text == "" {
+		return fmt.Sprintf("%v (0x%X)", StatusName(e.Status), uint32(e.Status))
+	}
+	return fmt.Sprintf("%v: %v", StatusName(e.Status), e.Text)
+}
+
+// Returns the name of a status code, statuses combining several error bits are joined by '|'
+func StatusName(status TPCANStatus) string {
+	if status == PCAN_ERROR_OK {
+		return "PCAN_ERROR_OK"
+	}
+	if name, found := statusNames[status]; found {
+		return name
+	}
+
+	var names []string
+	remaining := status
+	for _, bit := range statusBits {
+		if remaining&bit.status == bit.status {
+			names = append(names, bit.name)
+			remaining &^= bit.status
+		}
+	}
+	if remaining != 0 {
+		names = append(names, fmt.Sprintf("0x%X", uint32(remaining)))
+	}
+	return strings.Join(names, "|")
+}
+
+// status codes in the order they are matched against a combined status, handle errors sharing bits come first
+var statusBits = []struct {
+	status TPCANStatus
+	name   string
+}{
+	{PCAN_ERROR_ILLCLIENT, "PCAN_ERROR_ILLCLIENT"},
+	{PCAN_ERROR_ILLNET, "PCAN_ERROR_ILLNET"},
+	{PCAN_ERROR_ILLHW, "PCAN_ERROR_ILLHW"},
+	{PCAN_ERROR_XMTFULL, "PCAN_ERROR_XMTFULL"},
+	{PCAN_ERROR_OVERRUN, "PCAN_ERROR_OVERRUN"},
+	{PCAN_ERROR_BUSLIGHT, "PCAN_ERROR_BUSLIGHT"},
+	{PCAN_ERROR_BUSHEAVY, "PCAN_ERROR_BUSHEAVY"},
+	{PCAN_ERROR_BUSPASSIVE, "PCAN_ERROR_BUSPASSIVE"},
+	{PCAN_ERROR_BUSOFF, "PCAN_ERROR_BUSOFF"},
+	{PCAN_ERROR_QRCVEMPTY, "PCAN_ERROR_QRCVEMPTY"},
+	{PCAN_ERROR_QOVERRUN, "PCAN_ERROR_QOVERRUN"},
+	{PCAN_ERROR_QXMTFULL, "PCAN_ERROR_QXMTFULL"},
+	{PCAN_ERROR_REGTEST, "PCAN_ERROR_REGTEST"},
+	{PCAN_ERROR_NODRIVER, "PCAN_ERROR_NODRIVER"},
+	{PCAN_ERROR_HWINUSE, "PCAN_ERROR_HWINUSE"},
+	{PCAN_ERROR_NETINUSE, "PCAN_ERROR_NETINUSE"},
+	{PCAN_ERROR_RESOURCE, "PCAN_ERROR_RESOURCE"},
+	{PCAN_ERROR_ILLPARAMTYPE, "PCAN_ERROR_ILLPARAMTYPE"},
+	{PCAN_ERROR_ILLPARAMVAL, "PCAN_ERROR_ILLPARAMVAL"},
+	{PCAN_ERROR_UNKNOWN, "PCAN_ERROR_UNKNOWN"},
+	{PCAN_ERROR_ILLDATA, "PCAN_ERROR_ILLDATA"},
+	{PCAN_ERROR_ILLMODE, "PCAN_ERROR_ILLMODE"},
+	{PCAN_ERROR_CAUTION, "PCAN_ERROR_CAUTION"},
+	{PCAN_ERROR_INITIALIZE, "PCAN_ERROR_INITIALIZE"},
+	{PCAN_ERROR_ILLOPERATION, "PCAN_ERROR_ILLOPERATION"},
+}
+
+// names of the single status codes
+var statusNames = func() map[TPCANStatus]string {
+	names := make(map[TPCANStatus]string, len(statusBits))
+	for _, bit := range statusBits {
+		names[bit.status] = bit.name
+	}
+	return names
+}()
+
+// helper function to build the error of an API call from its status and syscall error
+// Note: The status is only turned into an error if EnrichStatusErrors is set and it is no flow control status
+func statusErr(status TPCANStatus, errno error) error {
+	if err := syscallErr(errno); err != nil || !EnrichStatusErrors || isFlowControlStatus(status) {
+		return err
+	}
+	text, _ := GetErrorString(status, LanguageEnglish)
+	return &StatusError{Status: status, Text: text}
+}
+
+// checks if a status is OK or only signals a state callers handle as part of the normal flow
+func isFlowControlStatus(status TPCANStatus) bool {
+	return status&^(PCAN_ERROR_QRCVEMPTY|PCAN_ERROR_QOVERRUN|PCAN_ERROR_OVERRUN|PCAN_ERROR_QXMTFULL|PCAN_ERROR_XMTFULL) == 0
+}
@@ -0,0 +1,92 @@
+package pcan
+
+import (
+	"errors"
+	"runtime"
+	"time"
+)
+
+/* Transmission of messages at a given time of the device timer. */
+
+const (
+	timeReferenceRefresh = time.Second            // minimum age of the time reference before a received message replaces it
+	timedWriteSpin       = 500 * time.Microsecond // time before the target in which the host timer yields instead of sleeping
+)
+
+// Mechanism used by WriteAt() to send a message at the requested time
+type TransmitMechanism int
+
+const (
+	TransmitHardware  TransmitMechanism = iota // The device sends the message at the requested time of its timer (not offered by the PCAN-Basic driver yet)
+	TransmitHostTimer                          // A goroutine of this package calls Write() at the requested time
+)
+
+// Returns the name of the mechanism
+func (m TransmitMechanism) String() string {
+	if m == TransmitHardware {
+		return "hardware"
+	}
+	return "host timer"
+}
+
+// Result of a message sent by WriteAt()
+type WriteResult struct {
+	Status TPCANStatus // Status returned by Write()
+	Err    error       // Error returned by Write()
+	Time   time.Time   // Host time Write() was called at
+}
+
+// maps the device timer to host time, taken from a received message
+type timeReference struct {
+	deviceMicros uint64    // timestamp of the received message
+	host         time.Time // host time the message was read at
+}
+
+// updates the time reference with a message read at the given host time
+func (p *TPCANBus) updateTimeReference(timestamp *TPCANTimestamp, now time.Time) {
+	if ref := p.timeRef.Load(); ref != nil && now.Sub(ref.host) < timeReferenceRefresh {
+		return
+	}
+	p.timeRef.Store(&timeReference{deviceMicros: timestamp.TotalMicros(), host: now})
+}
+
+// Returns the host time corresponding to a time of the device timer, false if no message was received yet
+// Note: The device timer is mapped with the last received message, so the result is late by the time the message
+// spent in the receive queue before it was read
+func (p *TPCANBus) DeviceTimeToHost(deviceMicros uint64) (time.Time, bool) {
+	ref := p.timeRef.Load()
+	if ref == nil {
+		return time.Time{}, false
+	}
+	return ref.host.Add(time.Duration(int64(deviceMicros)-int64(ref.deviceMicros)) * time.Microsecond), true
+}
+
+// Sends a message at the given time of the device timer, e.g. for stimuli at a fixed distance to a received message
+// msg: Message to send, it is copied so the caller may reuse it right after the call
+// deviceTime: Time of the device timer in microseconds as returned by TPCANTimestamp.TotalMicros()
+// Note: The PCAN-Basic driver offers no timed transmission, so TransmitHostTimer is always used: a goroutine waits
+// until the host time mapped to deviceTime (see DeviceTimeToHost) and calls Write(). The wait ends by yielding
+// instead of sleeping to keep the jitter low, it is still subject to the scheduling of the host. A time in the
+// past sends the message immediately. The returned channel receives the result and is closed afterwards.
+func (p *TPCANBus) WriteAt(msg *TPCANMsg, deviceTime uint64) (TransmitMechanism, <-chan WriteResult, error) {
+	target, ok := p.DeviceTimeToHost(deviceTime)
+	if !ok {
+		return TransmitHostTimer, nil, errors.New("device time is not mapped to host time yet, no message was received")
+	}
+
+	frame := *msg
+	results := make(chan WriteResult, 1)
+	go func() {
+		defer close(results)
+		if wait := time.Until(target) - timedWriteSpin; wait > 0 {
+			time.Sleep(wait)
+		}
+		for time.Now().Before(target) {
+			runtime.Gosched()
+		}
+		now := time.Now()
+		status, err := p.Write(&frame)
+		results <- WriteResult{Status: status, Err: err, Time: now}
+	}()
+	return TransmitHostTimer, results, nil
+}
@@ -0,0 +1,103 @@
+package pcan
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+/* Limiting the amount of trace files written by a segmented trace. */
+
+const tracePruneInterval = 5 * time.Second // time between two checks of the trace directory
+
+// interface part of the channel names by the bits of a handle above the channel number
+var traceBusNames = map[TPCANHandle]string{0x2: "ISA", 0x3: "DNG", 0x4: "PCI", 0x5: "USB", 0x6: "PCC", 0x8: "LAN"}
+
+// returns the channel name the driver uses in the trace file names, e.g. PCAN_USBBUS1, empty for an unknown handle
+func traceChannelName(handle TPCANHandle) string {
+	group, channel := handle>>4, handle&0x0F
+	if handle > 0xFF {
+		group, channel = handle>>8, handle&0xFF
+	}
+	bus, ok := traceBusNames[group]
+	if !ok || channel == 0 {
+		return ""
+	}
+	return fmt.Sprintf("PCAN_%vBUS%v", bus, uint16(channel))
+}
+
+// starts deleting the oldest trace files of the channel in the directory beyond maxFiles until stopTracePruning is called
+// channel: Channel name used in the trace file names, see traceChannelName()
+func (p *TPCANBus) startTracePruning(dir string, channel string, maxFiles int) {
+	p.stopTracePruning()
+
+	stop := make(chan struct{})
+	p.traceStop = stop
+	go func() {
+		ticker := time.NewTicker(tracePruneInterval)
+		defer ticker.Stop()
+		for {
+			pruneTraceFiles(dir, channel, maxFiles)
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// stops a running pruning of trace files
+func (p *TPCANBus) stopTracePruning() {
+	if p.traceStop != nil {
+		close(p.traceStop)
+		p.traceStop = nil
+	}
+}
+
+// checks if a file is a trace file written by the driver for the channel, the channel name is a "_" separated part of
+// its name, e.g. PCAN_USBBUS1.trc or 20240131_120000_PCAN_USBBUS1_2.trc
+func isChannelTraceFile(name string, channel string) bool {
+	if !strings.EqualFold(filepath.Ext(name), ".trc") {
+		return false
+	}
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+	return stem == channel || strings.HasPrefix(stem, channel+"_") || strings.HasSuffix(stem, "_"+channel) ||
+		strings.Contains(stem, "_"+channel+"_")
+}
+
+// deletes the oldest trace files of the channel in the directory so at most maxFiles are kept
+// Note: Errors are ignored, the next check tries again
+func pruneTraceFiles(dir string, channel string, maxFiles int) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	type traceFile struct {
+		path    string
+		modTime time.Time
+	}
+	var files []traceFile
+	for _, entry := range entries {
+		if entry.IsDir() || !isChannelTraceFile(entry.Name(), channel) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, traceFile{path: filepath.Join(dir, entry.Name()), modTime: info.ModTime()})
+	}
+	if len(files) <= maxFiles {
+		return
+	}
+
+	slices.SortFunc(files, func(a, b traceFile) int { return a.modTime.Compare(b.modTime) })
+	for _, file := range files[:len(files)-maxFiles] {
+		_ = os.Remove(file.path)
+	}
+}
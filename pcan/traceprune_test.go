@@ -0,0 +1,96 @@
+package pcan
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+/* Tests of limiting the amount of trace files. */
+
+func TestTraceChannelName(t *testing.T) {
+	tests := []struct {
+		handle TPCANHandle
+		want   string
+	}{
+		{PCAN_USBBUS1, "PCAN_USBBUS1"},
+		{PCAN_USBBUS16, "PCAN_USBBUS16"},
+		{PCAN_PCIBUS9, "PCAN_PCIBUS9"},
+		{PCAN_ISABUS8, "PCAN_ISABUS8"},
+		{PCAN_DNGBUS1, "PCAN_DNGBUS1"},
+		{PCAN_PCCBUS2, "PCAN_PCCBUS2"},
+		{PCAN_LANBUS10, "PCAN_LANBUS10"},
+		{PCAN_NONEBUS, ""},
+		{TPCANHandle(0x71), ""},
+	}
+	for _, test := range tests {
+		if got := traceChannelName(test.handle); got != test.want {
+			t.Errorf("traceChannelName(0x%X) = %q, want %q", test.handle, got, test.want)
+		}
+	}
+}
+
+func TestPruneTraceFilesOfChannel(t *testing.T) {
+	dir := t.TempDir()
+	// oldest first
+	names := []string{
+		"PCAN_USBBUS1.trc",
+		"PCAN_USBBUS2_1.trc",
+		"20240131_120000_PCAN_USBBUS1_1.trc",
+		"PCAN_USBBUS10_1.trc",
+		"20240131_120000_PCAN_USBBUS1_2.trc",
+		"notes.txt",
+		"20240131_120000_PCAN_USBBUS1_3.trc",
+	}
+	start := time.Now().Add(-time.Hour)
+	for i, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		modTime := start.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pruneTraceFiles(dir, "PCAN_USBBUS1", 2)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kept []string
+	for _, entry := range entries {
+		kept = append(kept, entry.Name())
+	}
+	want := []string{
+		"20240131_120000_PCAN_USBBUS1_2.trc",
+		"20240131_120000_PCAN_USBBUS1_3.trc",
+		"PCAN_USBBUS10_1.trc",
+		"PCAN_USBBUS2_1.trc",
+		"notes.txt",
+	}
+	if !slices.Equal(kept, want) {
+		t.Errorf("kept files = %v, want %v", kept, want)
+	}
+}
+
+func TestStartTraceMaxFilesRequiresSegments(t *testing.T) {
+	calls := countCalls(t, map[string]**apiProc{"CAN_SetValue": &pHandleSetValue})
+	bus := &TPCANBus{Handle: PCAN_USBBUS1}
+
+	status, err := bus.StartTraceWithConfig(t.TempDir(), TraceConfig{MaxFiles: 3})
+	if err == nil || status != PCAN_ERROR_ILLPARAMVAL {
+		t.Errorf("StartTraceWithConfig() = %v, %v, want PCAN_ERROR_ILLPARAMVAL and an error", status, err)
+	}
+	if len(calls) != 0 {
+		t.Errorf("driver calls = %v, the trace must not be started", calls)
+	}
+	if bus.traceStop != nil {
+		bus.stopTracePruning()
+		t.Error("pruning was started")
+	}
+}
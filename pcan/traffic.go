@@ -0,0 +1,104 @@
+package pcan
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+/* Bus load analysis of the traffic observed on a bus. */
+
+// Traffic statistics of a single message identifier
+type IDTraffic struct {
+	ID             TPCANMsgID    `json:"id"`
+	Extended       bool          `json:"extended"`
+	Count          int           `json:"count"`           // Amount of received messages
+	AvgPeriod      time.Duration `json:"avg_period_ns"`   // Average time between two messages, zero if received only once
+	PayloadChanges int           `json:"payload_changes"` // Amount of messages whose payload differed from the previous one
+	Bits           int           `json:"bits"`            // Worst case amount of bits on the bus including stuff bits
+}
+
+// Result of a traffic analysis
+type TrafficReport struct {
+	Duration    time.Duration `json:"duration_ns"` // Duration of the analysis
+	BitRate     uint32        `json:"bit_rate"`    // Nominal bit rate of the bus in bits per second
+	TotalFrames int           `json:"total_frames"`
+	TotalBits   int           `json:"total_bits"`  // Worst case amount of bits on the bus including stuff bits
+	Utilization float64       `json:"utilization"` // Worst case bus load as fraction of the nominal bit rate (0..1)
+	IDs         []IDTraffic   `json:"ids"`         // Statistics per identifier ordered by identifier
+}
+
+// Listens on the bus for the given duration and reports the traffic per identifier and the bus load
+// duration: Time to listen on the bus
+// Note: The bus load is estimated with the worst case amount of stuff bits of the received frames
+func AnalyzeTraffic(p *TPCANBus, duration time.Duration) (TrafficReport, error) {
+	bitRate, ok := BaudrateBitsPerSecond(p.Baudrate)
+	if !ok {
+		return TrafficReport{}, fmt.Errorf("bit rate of baud rate register value 0x%X is unknown", p.Baudrate)
+	}
+
+	type idState struct {
+		traffic IDTraffic
+		first   TPCANTimestamp
+		last    TPCANTimestamp
+		data    [LENGTH_DATA_CAN_MESSAGE]byte
+		dlc     uint8
+	}
+	states := make(map[TPCANMsgID]*idState)
+	report := TrafficReport{Duration: duration, BitRate: bitRate}
+
+	startTime := time.Now()
+	endTime := startTime.Add(duration)
+	for {
+		remaining := time.Until(endTime)
+		if remaining <= 0 {
+			break
+		}
+		_, msg, timestamp, err := p.ReadWithTimeout(int(max(remaining.Milliseconds(), 1)))
+		if err != nil {
+			return report, err
+		}
+		if msg == nil {
+			continue
+		}
+		kind := ClassifyMsgType(msg.MsgType)
+		if kind != FrameKindData && kind != FrameKindRTR {
+			continue
+		}
+
+		extended := msg.MsgType&PCAN_MESSAGE_EXTENDED != 0
+		dataLen := int(msg.DLC)
+		if kind == FrameKindRTR {
+			dataLen = 0
+		}
+		bits := FrameBitsWorstCase(extended, dataLen)
+
+		state, seen := states[msg.ID]
+		if !seen {
+			state = &idState{traffic: IDTraffic{ID: msg.ID, Extended: extended}, first: *timestamp}
+			states[msg.ID] = state
+		} else if state.dlc != msg.DLC || state.data != msg.Data {
+			state.traffic.PayloadChanges++
+		}
+		state.traffic.Count++
+		state.traffic.Bits += bits
+		state.last = *timestamp
+		state.data = msg.Data
+		state.dlc = msg.DLC
+
+		report.TotalFrames++
+		report.TotalBits += bits
+	}
+
+	for _, state := range states {
+		if state.traffic.Count > 1 {
+			state.traffic.AvgPeriod = TimestampDelta(state.first, state.last) / time.Duration(state.traffic.Count-1)
+		}
+		report.IDs = append(report.IDs, state.traffic)
+	}
+	sort.Slice(report.IDs, func(a, b int) bool { return report.IDs[a].ID < report.IDs[b].ID })
+	if duration > 0 {
+		report.Utilization = float64(report.TotalBits) / (float64(bitRate) * duration.Seconds())
+	}
+	return report, nil
+}
@@ -0,0 +1,78 @@
+package pcan
+
+import (
+	"context"
+	"time"
+)
+
+/* Capturing of the traffic around an event of interest, started and stopped by matching messages. */
+
+// Configures the window recorded by CaptureTriggered()
+type TriggerConfig struct {
+	Start       Filter // Message starting the recording, it is the first recorded message after the pre-trigger messages; nil starts with the first message
+	Stop        Filter // Message stopping the recording, it is the last recorded message; nil to stop by PostTrigger only
+	PreTrigger  int    // Amount of messages received before the start message which are recorded as well
+	PostTrigger int    // Amount of messages recorded after the start message before the recording stops; zero for no limit
+}
+
+// Records the messages of a bus around an event, e.g. starting when ID 0x7E8 appears and stopping 500 messages later
+// ctx: Cancelling stops waiting for the start message or the recording
+// bus: Already initialized bus to read from
+// trigger: Start and stop conditions of the recording
+// Note: Blocks until the recording stopped. If ctx is cancelled, the messages recorded so far are returned without error,
+// if reading fails they are returned together with the error. Without Stop and PostTrigger, ctx ends the recording.
+func CaptureTriggered(ctx context.Context, bus *TPCANBus, trigger TriggerConfig) ([]TPCANMsg, []TPCANTimestamp, error) {
+	var msgs []TPCANMsg
+	var timestamps []TPCANTimestamp
+	var pre *RingCapture
+	if trigger.PreTrigger > 0 {
+		pre = NewRingCapture(nil, trigger.PreTrigger)
+	}
+
+	recording := false
+	afterStart := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return msgs, timestamps, nil
+		default:
+		}
+
+		_, msg, timestamp, err := bus.ReadWithTimeout(monitorReadTimeout)
+		if err != nil {
+			return msgs, timestamps, err
+		}
+		if msg == nil || timestamp == nil {
+			continue
+		}
+
+		if !recording {
+			if trigger.Start != nil && !trigger.Start.Allow(msg) {
+				if pre != nil {
+					pre.add(RingFrame{Msg: *msg, Timestamp: *timestamp, Received: time.Now()})
+				}
+				continue
+			}
+			recording = true
+			if pre != nil {
+				for _, frame := range pre.Snapshot() {
+					msgs = append(msgs, frame.Msg)
+					timestamps = append(timestamps, frame.Timestamp)
+				}
+			}
+			msgs = append(msgs, *msg)
+			timestamps = append(timestamps, *timestamp)
+			continue
+		}
+
+		msgs = append(msgs, *msg)
+		timestamps = append(timestamps, *timestamp)
+		afterStart++
+		if trigger.Stop != nil && trigger.Stop.Allow(msg) {
+			return msgs, timestamps, nil
+		}
+		if trigger.PostTrigger > 0 && afterStart >= trigger.PostTrigger {
+			return msgs, timestamps, nil
+		}
+	}
+}
@@ -1,5 +1,31 @@
 package pcan
 
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Strategy used to wait for received messages
+type ReadStrategy int
+
+const (
+	ReadStrategyEvent   ReadStrategy = iota // Waits for the receive event signaled by the driver
+	ReadStrategyPolling                     // Polls the receive queue with a sleep in between
+)
+
+// Returns the name of the strategy
+func (s ReadStrategy) String() string {
+	switch s {
+	case ReadStrategyEvent:
+		return "event"
+	case ReadStrategyPolling:
+		return "polling"
+	default:
+		return "unknown"
+	}
+}
+
 // Represents a PCAN message
 type TPCANMsg struct {
 	ID      TPCANMsgID                    // 11/29-bit message identifier
@@ -16,6 +42,25 @@ type TPCANTimestamp struct {
 	Micros         uint16 // Microseconds: 0..999
 }
 
+// Returns the total amount of microseconds represented by the timestamp
+func (t TPCANTimestamp) TotalMicros() uint64 {
+	return uint64(t.Micros) + 1000*uint64(t.Millis) + 0x100000000*1000*uint64(t.MillisOverflow)
+}
+
+// Creates a timestamp from a total amount of microseconds
+func TimestampFromMicros(micros uint64) TPCANTimestamp {
+	millis := micros / 1000
+	return TPCANTimestamp{
+		Millis:         uint32(millis),
+		MillisOverflow: uint16(millis >> 32),
+		Micros:         uint16(micros % 1000)}
+}
+
+// Returns the signed duration from timestamp a to timestamp b including roll-arounds of the milliseconds
+func TimestampDelta(a, b TPCANTimestamp) time.Duration {
+	return time.Duration(int64(b.TotalMicros())-int64(a.TotalMicros())) * time.Microsecond
+}
+
 // Represents a PCAN message from a FD capable hardware
 type TPCANMsgFD struct {
 	ID      TPCANMsgID
@@ -34,3 +79,134 @@ type TPCANChannelInformation struct {
 	DeviceID         uint32                         // Device number
 	ChannelCondition TPCANCHannelCondition          // Availability status of a PCAN-Channel
 }
+
+// Configuration of a trace recording
+// Note: The driver is not able to append to an existing trace file. Without Overwrite, starting a trace fails
+// if a file with the same name already exists, so a session never clobbers a previous one. Including date and
+// time into the file name creates a new file for every session.
+// Note: All TRACE_FILE_* bits documented for the driver are available. The file format version written by the
+// driver is not changed by any bit; DataLength only swaps the DLC column for the data length column, which is
+// listed in the column header of the file. The driver has no bus load column.
+type TraceConfig struct {
+	MaxFileSize uint32 // Trace is splitted in files with this maximum size in MB; zero for a single file (max is 100 MB)
+	IncludeDate bool   // Includes the date into the name of the trace file (TRACE_FILE_DATE)
+	IncludeTime bool   // Includes the start time into the name of the trace file (TRACE_FILE_TIME)
+	Overwrite   bool   // Overwrites available traces with the same name (TRACE_FILE_OVERWRITE)
+	MaxFiles    int    // Maximum amount of trace files of the channel kept while segmenting, requires MaxFileSize; zero keeps all files
+	DataLength  bool   // Writes the data length in bytes instead of the DLC code, relevant for CAN FD (TRACE_FILE_DATA_LENGTH)
+}
+
+// Returns the TRACE_FILE_* bits representing the configuration
+func (c TraceConfig) Flags() TPCANTraceFileValue {
+	flags := TRACE_FILE_SINGLE
+	if c.MaxFileSize > 0 {
+		flags |= TRACE_FILE_SEGMENTED
+	}
+	if c.IncludeDate {
+		flags |= TRACE_FILE_DATE
+	}
+	if c.IncludeTime {
+		flags |= TRACE_FILE_TIME
+	}
+	if c.Overwrite {
+		flags |= TRACE_FILE_OVERWRITE
+	}
+	if c.DataLength {
+		flags |= TRACE_FILE_DATA_LENGTH
+	}
+	return flags
+}
+
+// Options applied to a channel right after its initialization
+// Note: There is no option for the size of the receive queue, the PCAN-Basic driver has no parameter for it. The
+// queue size is fixed by the driver, read the queue regularly and check RxOverflows() for lost messages.
+type InitOptions struct {
+	AllowStatusFrames bool  // Receive PCAN status messages
+	AllowRTRFrames    bool  // Receive remote transmission request frames
+	AllowErrorFrames  bool  // Receive error frames
+	AllowEchoFrames   bool  // Receive echo frames of sent messages
+	ListenOnly        bool  // Only listen on the bus without acknowledging messages
+	Power5V           *bool // Turns the 5-Volt power supply on or off, left untouched if nil (only supported by some devices)
+}
+
+// Options applied to a FD channel by InitializeFDWithOptions before it is initialized
+type FDInitOptions struct {
+	BitrateAdapting bool // Adapts to the bit rate of a channel already used by another application (PCAN_BITRATE_ADAPTING)
+	NonISO          bool // Uses the non-ISO CAN FD frame format of legacy networks (FDModeNonISO), see Validate()
+}
+
+// Checks the options for combinations which can not be applied
+// Note: With bit rate adapting the frame format is taken from the running channel, so NonISO can not be requested.
+// The PCAN-Basic driver has no parameter selecting the non-ISO frame format, it is a setting of the device
+// (e.g. in its driver configuration), so NonISO always fails with ErrNotSupported.
+func (o FDInitOptions) Validate() error {
+	if o.BitrateAdapting && o.NonISO {
+		return errors.New("bit rate adapting and non-ISO mode are mutually exclusive")
+	}
+	if o.NonISO {
+		return fmt.Errorf("non-ISO CAN FD mode: %w", ErrNotSupported)
+	}
+	return nil
+}
+
+// Criteria to find a PCAN-Basic Channel, empty fields are not part of the lookup
+type LookUpOptions struct {
+	DeviceType       string            // Device type (see PCAN devices e.g. PCAN_USB)
+	DeviceID         string            // Device identifier
+	ControllerNumber string            // CAN controller 0-based index
+	IPAddress        string            // IP address (LAN channels only)
+	DeviceGUID       string            // Device unique identifier (USB channels only)
+	RequiredFeatures TPCANFeatureValue // Capabilities the found channel must have (FEATURE_*), checked after the lookup
+}
+
+// Options for replaying recorded messages
+type ReplayOptions struct {
+	Loop        bool    // Replays the recording repeatedly, otherwise it is replayed once
+	SpeedFactor float64 // Replay speed relative to the recording, e.g. 2 halves the delays; zero or below replays in real time
+	Count       int     // Amount of iterations if Loop is set, zero replays until the context is cancelled
+}
+
+// Capabilities of a PCAN device decoded from its FEATURE_* bitmask
+type ChannelFeatures struct {
+	FDCapable    bool              // Device supports flexible data-rate (CAN-FD)
+	DelayCapable bool              // Device supports a delay between sending frames (FPGA based USB devices)
+	IOCapable    bool              // Device supports I/O functionality for electronic circuits (USB-Chip devices)
+	Raw          TPCANFeatureValue // Undecoded bitmask as returned by the driver
+}
+
+// Decodes a FEATURE_* bitmask
+func DecodeFeatures(features TPCANFeatureValue) ChannelFeatures {
+	return ChannelFeatures{
+		FDCapable:    features&FEATURE_FD_CAPABLE != 0,
+		DelayCapable: features&FEATURE_DELAY_CAPABLE != 0,
+		IOCapable:    features&FEATURE_IO_CAPABLE != 0,
+		Raw:          features}
+}
+
+// Combined operation mode of a channel regarding transmission and reception of special frames
+//
+// Valid combinations (listen-only × echo × RTR):
+//
+//	ListenOnly  EchoFrames  RTRFrames
+//	false       false       false/true  normal operation
+//	false       true        false/true  normal operation, sent messages are read back as echo frames
+//	true        false       false/true  passive monitoring, nothing is sent or acknowledged
+//	true        true        -           invalid, a listen-only controller never transmits, so no echo is generated
+type ModeOptions struct {
+	ListenOnly bool // Controller does not take part in the bus communication (PCAN_LISTEN_ONLY)
+	EchoFrames bool // Sent messages are received as echo frames (PCAN_ALLOW_ECHO_FRAMES)
+	RTRFrames  bool // Remote transmission request frames are received (PCAN_ALLOW_RTR_FRAMES)
+}
+
+// Checks the mode for an invalid combination of options
+func (m ModeOptions) Validate() error {
+	if m.ListenOnly && m.EchoFrames {
+		return errors.New("echo frames are not available in listen-only mode as the controller does not transmit")
+	}
+	return nil
+}
+
+// Timing information of a PCAN device
+type TimerInfo struct {
+	Resolution time.Duration // Resolution of the timestamps of received messages
+}
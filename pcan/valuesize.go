@@ -0,0 +1,92 @@
+package pcan
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+/* Sanity checks of the buffer sizes passed to CAN_GetValue and CAN_SetValue for the well-known parameters. */
+
+// Returned by the value API calls if the buffer size does not fit the parameter, the driver is not called then
+var ErrBufferSize = errors.New("buffer size does not fit the parameter")
+
+// allowed buffer sizes of a parameter in bytes, maxSize zero means no upper bound
+type valueSize struct {
+	minSize, maxSize uint32
+}
+
+var (
+	numericValueSize = valueSize{minSize: 4, maxSize: 4}
+	filterValueSize  = valueSize{minSize: 8, maxSize: 8}
+	stringValueSize  = valueSize{minSize: 1}
+)
+
+// buffer sizes of the well-known parameters, parameters not listed are passed to the driver unchecked
+var valueSizes = map[TPCANParameter]valueSize{
+	PCAN_DEVICE_ID:                numericValueSize,
+	PCAN_5VOLTS_POWER:             numericValueSize,
+	PCAN_RECEIVE_EVENT:            {minSize: 4, maxSize: uint32(unsafe.Sizeof(uintptr(0)))}, // a handle, 4 bytes are accepted for compatibility
+	PCAN_MESSAGE_FILTER:           numericValueSize,
+	PCAN_API_VERSION:              stringValueSize,
+	PCAN_CHANNEL_VERSION:          stringValueSize,
+	PCAN_BUSOFF_AUTORESET:         numericValueSize,
+	PCAN_LISTEN_ONLY:              numericValueSize,
+	PCAN_LOG_LOCATION:             stringValueSize,
+	PCAN_LOG_STATUS:               numericValueSize,
+	PCAN_LOG_CONFIGURE:            numericValueSize,
+	PCAN_LOG_TEXT:                 stringValueSize,
+	PCAN_CHANNEL_CONDITION:        numericValueSize,
+	PCAN_HARDWARE_NAME:            stringValueSize,
+	PCAN_RECEIVE_STATUS:           numericValueSize,
+	PCAN_CONTROLLER_NUMBER:        numericValueSize,
+	PCAN_TRACE_LOCATION:           stringValueSize,
+	PCAN_TRACE_STATUS:             numericValueSize,
+	PCAN_TRACE_SIZE:               numericValueSize,
+	PCAN_TRACE_CONFIGURE:          numericValueSize,
+	PCAN_CHANNEL_IDENTIFYING:      numericValueSize,
+	PCAN_CHANNEL_FEATURES:         numericValueSize,
+	PCAN_BITRATE_ADAPTING:         numericValueSize,
+	PCAN_BITRATE_INFO:             numericValueSize,
+	PCAN_BITRATE_INFO_FD:          stringValueSize,
+	PCAN_BUSSPEED_NOMINAL:         numericValueSize,
+	PCAN_BUSSPEED_DATA:            numericValueSize,
+	PCAN_IP_ADDRESS:               stringValueSize,
+	PCAN_LAN_SERVICE_STATUS:       numericValueSize,
+	PCAN_ALLOW_STATUS_FRAMES:      numericValueSize,
+	PCAN_ALLOW_RTR_FRAMES:         numericValueSize,
+	PCAN_ALLOW_ERROR_FRAMES:       numericValueSize,
+	PCAN_INTERFRAME_DELAY:         numericValueSize,
+	PCAN_ACCEPTANCE_FILTER_11BIT:  filterValueSize,
+	PCAN_ACCEPTANCE_FILTER_29BIT:  filterValueSize,
+	PCAN_IO_DIGITAL_CONFIGURATION: numericValueSize,
+	PCAN_IO_DIGITAL_VALUE:         numericValueSize,
+	PCAN_IO_DIGITAL_SET:           numericValueSize,
+	PCAN_IO_DIGITAL_CLEAR:         numericValueSize,
+	PCAN_IO_ANALOG_VALUE:          numericValueSize,
+	PCAN_FIRMWARE_VERSION:         stringValueSize,
+	PCAN_ATTACHED_CHANNELS_COUNT:  numericValueSize,
+	PCAN_ALLOW_ECHO_FRAMES:        numericValueSize,
+	PCAN_DEVICE_PART_NUMBER:       stringValueSize,
+	PCAN_HARD_RESET_STATUS:        numericValueSize,
+	PCAN_LAN_CHANNEL_DIRECTION:    numericValueSize,
+	PCAN_DEVICE_GUID:              stringValueSize,
+}
+
+// checks the buffer size of a value API call against the size expected for the parameter
+func checkValueSize(param TPCANParameter, buffer unsafe.Pointer, bufferSize uint32) error {
+	if buffer == nil {
+		return fmt.Errorf("%w: buffer of parameter %v is nil", ErrBufferSize, param)
+	}
+	size, found := valueSizes[param]
+	if !found {
+		return nil
+	}
+	if bufferSize < size.minSize || (size.maxSize != 0 && bufferSize > size.maxSize) {
+		if size.minSize == size.maxSize {
+			return fmt.Errorf("%w: parameter %v needs %v bytes, got %v", ErrBufferSize, param, size.minSize, bufferSize)
+		}
+		return fmt.Errorf("%w: parameter %v got %v bytes", ErrBufferSize, param, bufferSize)
+	}
+	return nil
+}
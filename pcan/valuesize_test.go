@@ -0,0 +1,71 @@
+package pcan
+
+import (
+	"errors"
+	"testing"
+	"unsafe"
+)
+
+/* Tests of the buffer size checks of the value API calls. */
+
+func TestCheckValueSize(t *testing.T) {
+	var buffer [MAX_LENGHT_STRING_BUFFER]byte
+	handleSize := uint32(unsafe.Sizeof(uintptr(0)))
+
+	tests := []struct {
+		name    string
+		param   TPCANParameter
+		size    uint32
+		nilBuf  bool
+		wantErr bool
+	}{
+		{"numeric", PCAN_LISTEN_ONLY, 4, false, false},
+		{"numeric too small", PCAN_LISTEN_ONLY, 2, false, true},
+		{"numeric too large", PCAN_LISTEN_ONLY, 8, false, true},
+		{"numeric zero", PCAN_CHANNEL_CONDITION, 0, false, true},
+		{"filter", PCAN_ACCEPTANCE_FILTER_11BIT, 8, false, false},
+		{"filter too small", PCAN_ACCEPTANCE_FILTER_29BIT, 4, false, true},
+		{"string", PCAN_HARDWARE_NAME, MAX_LENGHT_STRING_BUFFER, false, false},
+		{"string of one byte", PCAN_HARDWARE_NAME, 1, false, false},
+		{"string empty", PCAN_API_VERSION, 0, false, true},
+		{"event of 4 bytes", PCAN_RECEIVE_EVENT, 4, false, false},
+		{"event of a handle", PCAN_RECEIVE_EVENT, handleSize, false, false},
+		{"event too large", PCAN_RECEIVE_EVENT, handleSize + 4, false, true},
+		{"event too small", PCAN_RECEIVE_EVENT, 2, false, true},
+		{"unknown parameter", TPCANParameter(0xFF), 3, false, false},
+		{"nil buffer", PCAN_LISTEN_ONLY, 4, true, true},
+		{"nil buffer of unknown parameter", TPCANParameter(0xFF), 4, true, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ptr := unsafe.Pointer(&buffer)
+			if test.nilBuf {
+				ptr = nil
+			}
+			err := checkValueSize(test.param, ptr, test.size)
+			if test.wantErr != (err != nil) {
+				t.Fatalf("checkValueSize(%v, %v) = %v, want an error %v", test.param, test.size, err, test.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrBufferSize) {
+				t.Errorf("checkValueSize(%v, %v) = %v, want ErrBufferSize", test.param, test.size, err)
+			}
+		})
+	}
+}
+
+func TestSetValueWrongSizeSkipsDriver(t *testing.T) {
+	called := false
+	stubProc(t, &pHandleSetValue, func(a ...uintptr) (uintptr, uintptr, error) {
+		called = true
+		return uintptr(PCAN_ERROR_OK), 0, nil
+	})
+
+	var value uint16
+	status, err := APISetValue(PCAN_USBBUS1, PCAN_LISTEN_ONLY, unsafe.Pointer(&value), uint32(unsafe.Sizeof(value)))
+	if status != PCAN_ERROR_ILLPARAMVAL || !errors.Is(err, ErrBufferSize) {
+		t.Errorf("APISetValue() = %v, %v, want PCAN_ERROR_ILLPARAMVAL and ErrBufferSize", status, err)
+	}
+	if called {
+		t.Error("driver was called with a wrong buffer size")
+	}
+}
@@ -0,0 +1,95 @@
+package pcan
+
+import (
+	"context"
+	"time"
+)
+
+/* Watching the device connection of a bus and recovering after a replug. */
+
+// Kind of a connection event
+type ConnectionEventKind int
+
+const (
+	ConnectionLost     ConnectionEventKind = iota // The device of the channel disappeared
+	ConnectionRestored                            // The device reappeared and the channel was initialized again
+	ReconnectFailed                               // The device reappeared but initializing the channel failed
+)
+
+// Event emitted by WatchAndRecover
+type ConnectionEvent struct {
+	Kind   ConnectionEventKind // Kind of the event
+	Status TPCANStatus         // Status of the failing call for ReconnectFailed
+	Err    error               // Error of the failing call for ReconnectFailed
+	Time   time.Time           // Time the event was detected
+}
+
+// Periodically checks the device connection and initializes the channel again when the device reappears
+// interval: Time between two connection checks
+// Note: The returned channel is closed after ctx is cancelled. A failed reconnect is retried on the next check.
+// Note: Reads of other goroutines wait while the channel is initialized again, see Reconnect()
+func (p *TPCANBus) WatchAndRecover(ctx context.Context, interval time.Duration) <-chan ConnectionEvent {
+	events := make(chan ConnectionEvent, 8)
+
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		emit := func(event ConnectionEvent) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		connected := true
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if connected {
+				_, present, err := p.IsConnected()
+				if err == nil && present {
+					continue
+				}
+				connected = false
+				if !emit(ConnectionEvent{Kind: ConnectionLost, Time: time.Now()}) {
+					return
+				}
+				continue
+			}
+
+			// wait for the device to reappear before initializing it again
+			status, cond, err := p.GetChannelCondition()
+			if status != PCAN_ERROR_OK || err != nil || !isReconnectable(cond) {
+				continue
+			}
+			status, err = p.Reconnect()
+			if status != PCAN_ERROR_OK || err != nil {
+				if !emit(ConnectionEvent{Kind: ReconnectFailed, Status: status, Err: err, Time: time.Now()}) {
+					return
+				}
+				continue
+			}
+			connected = true
+			if !emit(ConnectionEvent{Kind: ConnectionRestored, Status: status, Time: time.Now()}) {
+				return
+			}
+		}
+	}()
+
+	return events
+}
+
+// checks if a channel can be initialized again after its device reappeared
+// Note: A channel used by PCAN-View (PCAN_CHANNEL_PCANVIEW) is connectable as the driver shares it with PCAN-View,
+// a channel occupied by another application is not.
+func isReconnectable(cond TPCANCHannelCondition) bool {
+	return cond == PCAN_CHANNEL_AVAILABLE || cond == PCAN_CHANNEL_PCANVIEW
+}
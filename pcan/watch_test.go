@@ -0,0 +1,78 @@
+package pcan
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+/* Tests of the recovery of a replugged device. */
+
+func TestIsReconnectable(t *testing.T) {
+	tests := []struct {
+		cond TPCANCHannelCondition
+		want bool
+	}{
+		{PCAN_CHANNEL_UNAVAILABLE, false},
+		{PCAN_CHANNEL_AVAILABLE, true},
+		{PCAN_CHANNEL_OCCUPIED, false},
+		{PCAN_CHANNEL_PCANVIEW, true},
+		{TPCANCHannelCondition(0x05), false},
+	}
+	for _, test := range tests {
+		if got := isReconnectable(test.cond); got != test.want {
+			t.Errorf("isReconnectable(%v) = %v, want %v", test.cond, got, test.want)
+		}
+	}
+}
+
+func TestReconnectWaitsForRead(t *testing.T) {
+	var initialized atomic.Int32
+	stubProc(t, &pHandleUninitialize, func(a ...uintptr) (uintptr, uintptr, error) { return uintptr(PCAN_ERROR_OK), 0, nil })
+	stubProc(t, &pHandleInitialize, func(a ...uintptr) (uintptr, uintptr, error) {
+		initialized.Add(1)
+		return uintptr(PCAN_ERROR_OK), 0, nil
+	})
+	bus := &TPCANBus{Handle: PCAN_USBBUS3, Baudrate: PCAN_BAUD_500K}
+	registerBus(t, bus)
+
+	reading, release := make(chan struct{}), make(chan struct{})
+	oldRead := apiRead
+	apiRead = func(handle TPCANHandle) (TPCANStatus, TPCANMsg, TPCANTimestamp, error) {
+		close(reading)
+		<-release
+		return PCAN_ERROR_QRCVEMPTY, TPCANMsg{}, TPCANTimestamp{}, nil
+	}
+	t.Cleanup(func() { apiRead = oldRead })
+
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		_, _, _, _ = bus.Read()
+	}()
+	<-reading
+
+	reconnected := make(chan TPCANStatus)
+	go func() {
+		status, _ := bus.Reconnect()
+		reconnected <- status
+	}()
+
+	select {
+	case <-reconnected:
+		t.Fatal("Reconnect() returned while a read was running")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if initialized.Load() != 0 {
+		t.Fatal("channel was initialized again while a read was running")
+	}
+
+	close(release)
+	<-readDone
+	if status := <-reconnected; status != PCAN_ERROR_OK {
+		t.Errorf("Reconnect() = %v, want PCAN_ERROR_OK", status)
+	}
+	if initialized.Load() != 1 {
+		t.Errorf("channel was initialized %v times, want once", initialized.Load())
+	}
+}